@@ -0,0 +1,915 @@
+package chess
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+
+	// Internal references
+	"goche/utility"
+)
+
+// The layout of Board.gameState
+const (
+	fullMoveMask    uint32 = 0b11111111110000000000000000000000
+	halfMoveMask    uint32 = 0b00000000001111111111000000000000
+	enPassantMask   uint32 = 0b00000000000000000000111111000000
+	castlingMask_WK uint32 = 0b00000000000000000000000000100000
+	castlingMask_WQ uint32 = 0b00000000000000000000000000010000
+	castlingMask_BK uint32 = 0b00000000000000000000000000001000
+	castlingMask_BQ uint32 = 0b00000000000000000000000000000100
+	blackMask       uint32 = 0b00000000000000000000000000000010
+	whiteMask       uint32 = 0b00000000000000000000000000000001
+)
+
+const (
+	fullMoveXOR  uint32 = 0b00000000001111111111111111111111
+	halfMoveXOR  uint32 = 0b11111111110000000000111111111111
+	enPassantXOR uint32 = 0b11111111111111111111000000111111
+)
+
+const (
+	fullMoveShift  = 22
+	halfMoveShift  = 12
+	enPassantShift = 6
+
+	fullMoveLSB = 0b00000000010000000000000000000000
+	halfMoveLSB = 0b00000000000000000001000000000000
+)
+
+// Board is a chess position: the placement of the pieces, the side to move, castling rights, any en
+// passant square, and the clocks. The zero value is not a valid position; use NewBoard.
+type Board struct {
+	// Partly made up of elements recognisable from FEN and the rest is transient
+	blackPieces uint64
+	whitePieces uint64
+	pawns       uint64
+	knights     uint64
+	bishops     uint64
+	rooks       uint64
+	queens      uint64
+	kings       uint64
+	gameState   uint32
+	hash        uint64
+
+	// gameState is designed to fit into a 32-bit uint, from lsb to msb:
+	//  2 bits  whose turn it is (01 = white, 10 = black. Two bits in case this buys some other advantage)
+	//  4 bits castling			 (1111 for KQkq)
+	//  6 bits enpassant		 (1<<x == uint64 square, 0 for none as that is not a valid enpassant square)
+	// 10 bits halfmove			 (enough for 1024, which should be enough)
+	// 10 bits fullmove			 (enough for 1024, which should be enough)
+}
+
+// NewBoard creates a board from a position in Forsyth-Edwards Notation. The half move clock and full
+// move number may be left out, as in EPD.
+func NewBoard(fen string) (*Board, error) {
+	if fen == "" {
+		return nil, fmt.Errorf("missing FEN string")
+	}
+
+	// Create the object and then populate it from the FEN string
+	board := &Board{}
+
+	type FenComponent int
+
+	const (
+		piecePlacement FenComponent = iota
+		activeColor
+		castlingRights
+		enPassantSquare
+		halfMoveClock
+		fullMoveNumber
+	)
+
+	// We are going to assume that the FEN string is more or less well formed, not least because we
+	// expect that it is being provided by another piece of software that also conforms to UCI
+
+	currentComponent := piecePlacement
+	remainder := fen
+
+	var component string
+	for {
+		component, remainder = utility.SplitNextWord(remainder)
+
+		switch currentComponent {
+		case piecePlacement:
+			// Piece placement starts on the 8th rank, 1st file
+			reminderBit := indexToBitboard(squareToIndex[uint32]("a8"))
+			bitboardBit := reminderBit
+
+			// Parse the piece placement section of the FEN string
+			for _, character := range component {
+				if character == '/' {
+					// Line break - move to next rank (towards LSB)
+					reminderBit >>= 8
+					bitboardBit = reminderBit
+					continue
+				}
+
+				// Empty square(s)
+				if unicode.IsDigit(character) {
+					bitboardBit <<= uint64(character - '0')
+					continue
+				}
+
+				// Piece color
+				if unicode.IsLower(character) {
+					board.blackPieces |= bitboardBit
+				} else if unicode.IsUpper(character) {
+					board.whitePieces |= bitboardBit
+				}
+
+				// Piece type
+				switch unicode.ToUpper(character) {
+				case 'P':
+					board.pawns |= bitboardBit
+
+				case 'N':
+					board.knights |= bitboardBit
+
+				case 'B':
+					board.bishops |= bitboardBit
+
+				case 'R':
+					board.rooks |= bitboardBit
+
+				case 'Q':
+					board.queens |= bitboardBit
+
+				case 'K':
+					board.kings |= bitboardBit
+				}
+
+				bitboardBit <<= 1
+			}
+
+		case activeColor:
+			board.gameState |= utility.If(strings.Contains(component, "w"), whiteMask, blackMask)
+
+		case castlingRights:
+			if strings.Contains(component, "K") {
+				board.gameState |= castlingMask_WK
+			}
+			if strings.Contains(component, "Q") {
+				board.gameState |= castlingMask_WQ
+			}
+			if strings.Contains(component, "k") {
+				board.gameState |= castlingMask_BK
+			}
+			if strings.Contains(component, "q") {
+				board.gameState |= castlingMask_BQ
+			}
+
+		case enPassantSquare:
+			if component == "-" {
+				board.clearEnPassantIndex()
+			} else if !isSquareString(component) {
+				return nil, fmt.Errorf("bad en passant square: %s", component)
+			} else {
+				value := squareToIndex[uint32](component)
+				board.setEnPassantIndex(value)
+			}
+
+		case halfMoveClock:
+			value, _ := strconv.Atoi(component)
+			board.setHalfMoveClock(uint32(value))
+
+		case fullMoveNumber:
+			value, _ := strconv.Atoi(component)
+			board.setFullMoveNumber(uint32(value))
+		}
+
+		// Done?
+		if currentComponent == fullMoveNumber {
+			break
+		}
+
+		// The clocks are often left out (e.g. in EPD), in which case they take their initial values
+		if remainder == "" && currentComponent >= enPassantSquare {
+			if currentComponent == enPassantSquare {
+				board.setHalfMoveClock(0)
+			}
+			board.setFullMoveNumber(1)
+			break
+		}
+
+		// Step onto the next item
+		currentComponent++
+		if remainder == "" {
+			return nil, fmt.Errorf("unexpected end of FEN string")
+		}
+	}
+
+	if err := board.validate(); err != nil {
+		return nil, err
+	}
+
+	board.hash = board.computeHash()
+
+	return board, nil
+}
+
+// validate checks that a position read from FEN is one that can arise in a game
+func (b *Board) validate() error {
+	if bits.OnesCount64(b.kings&b.whitePieces) != 1 || bits.OnesCount64(b.kings&b.blackPieces) != 1 {
+		return fmt.Errorf("each side must have exactly one king")
+	}
+
+	if b.pawns&(WhitePawnPromotionMask|BlackPawnPromotionMask) != 0 {
+		return fmt.Errorf("pawns cannot be on the first or last rank")
+	}
+
+	if b.whitePieces&b.blackPieces != 0 {
+		return fmt.Errorf("squares cannot hold pieces of both colors")
+	}
+
+	// The side that has just moved cannot have left its king in check
+	if b.isSquareAttacked(b.KingIndex(!b.IsWhiteToMove()), b.IsWhiteToMove()) {
+		return fmt.Errorf("the side not to move is in check")
+	}
+
+	return nil
+}
+
+// GetMoves appends the legal moves in the position to moveList and returns it
+func (board *Board) GetMoves(moveList []Move) ([]Move, error) {
+	// Generate pseudo-legal moves, then keep only those that do not leave our own king in check
+	start := len(moveList)
+	moveList = board.getPseudoLegalMoves(moveList)
+
+	white := board.IsWhiteToMove()
+	legal := moveList[:start]
+	for _, move := range moveList[start:] {
+		undo := board.MakeMove(move)
+		if !board.isSquareAttacked(board.KingIndex(white), !white) {
+			legal = append(legal, move)
+		}
+		board.UnmakeMove(undo)
+	}
+
+	return legal, nil
+}
+
+// GetCaptures appends the legal captures, including en passant, and promotions in the position to
+// moveList and returns it. These are the moves that change the material balance.
+func (board *Board) GetCaptures(moveList []Move) ([]Move, error) {
+	start := len(moveList)
+	moveList = board.getPseudoLegalMoves(moveList)
+
+	white := board.IsWhiteToMove()
+	legal := moveList[:start]
+	for _, move := range moveList[start:] {
+		if !move.IsPromotion() && !board.IsCapture(move) {
+			continue
+		}
+
+		undo := board.MakeMove(move)
+		if !board.isSquareAttacked(board.KingIndex(white), !white) {
+			legal = append(legal, move)
+		}
+		board.UnmakeMove(undo)
+	}
+
+	return legal, nil
+}
+
+// IsCapture reports whether the move takes a piece, including by en passant
+func (b *Board) IsCapture(move Move) bool {
+	to := int(move.To())
+	if b.Occupied()&(1<<to) != 0 {
+		return true
+	}
+
+	return to == int(b.getEnPassantIndex()) && to != 0 && b.pawns&(1<<move.From()) != 0
+}
+
+// getPseudoLegalMoves appends the moves in the position that obey the rules of movement for each piece,
+// but which might leave the side to move in check
+func (board *Board) getPseudoLegalMoves(moveList []Move) []Move {
+	// Source and Target for player and opponent
+	sourceMask := utility.If(board.IsWhiteToMove(), board.whitePieces, board.blackPieces)
+	targetMask := utility.If(board.IsWhiteToMove(), board.blackPieces, board.whitePieces)
+
+	// Generate all possible moves
+	moveList = board.generatePawnMoves(moveList, sourceMask, targetMask)
+	moveList = board.generateKnightMoves(moveList, sourceMask, targetMask)
+	moveList = board.generateSlidingMoves(moveList, board.bishops|board.queens, sourceMask, targetMask, BishopAttacks)
+	moveList = board.generateSlidingMoves(moveList, board.rooks|board.queens, sourceMask, targetMask, RookAttacks)
+	moveList = board.generateKingMoves(moveList, sourceMask, targetMask)
+
+	return moveList
+}
+
+func (b *Board) generatePawnMoves(moveList []Move, sourceMask uint64, targetMask uint64) []Move {
+	anyPiece := sourceMask | targetMask
+	pieceSet := b.pawns & sourceMask
+	white := b.IsWhiteToMove()
+	direction := utility.If(white, 8, -8)
+	promotionMask := utility.If[uint64](white, WhitePawnPromotionMask, BlackPawnPromotionMask)
+
+	// Pawns can capture onto the en passant square as if it were occupied
+	captureTargets := targetMask
+	if b.getEnPassantIndex() != 0 {
+		captureTargets |= indexToBitboard(b.getEnPassantIndex())
+	}
+
+	var pieceIndex int
+	var targetIndex int
+
+	// For each piece
+	for bitScanReverse(&pieceIndex, pieceSet) {
+		pieceSet ^= 1 << pieceIndex
+
+		// Pawns never stand on the last rank, so a single step is always on the board
+		targetIndex = pieceIndex + direction
+		if 1<<targetIndex&anyPiece == 0 {
+			moveList = appendPawnMoves(moveList, pieceIndex, targetIndex, promotionMask)
+
+			// Those that could do this move might also be able to double-slide
+			doubleSlideMask := utility.If(white, pieceMoveMasks.WhitePawnDoubleSlideMask[pieceIndex], pieceMoveMasks.BlackPawnDoubleSlideMask[pieceIndex])
+			if doubleSlideMask != 0 && doubleSlideMask&anyPiece == 0 {
+				moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(targetIndex+direction)))
+			}
+		}
+
+		// Captures, including ep
+		targetSquares := utility.If(white, pieceMoveMasks.WhitePawnCaptureMask[pieceIndex], pieceMoveMasks.BlackPawnCaptureMask[pieceIndex])
+		targetSquares &= captureTargets
+		for bitScanReverse(&targetIndex, targetSquares) {
+			targetSquares ^= 1 << targetIndex
+
+			moveList = appendPawnMoves(moveList, pieceIndex, targetIndex, promotionMask)
+		}
+	}
+
+	return moveList
+}
+
+// appendPawnMoves adds a pawn move, expanding it to each possible promotion if it reaches the last rank
+func appendPawnMoves(moveList []Move, pieceIndex int, targetIndex int, promotionMask uint64) []Move {
+	if 1<<targetIndex&promotionMask == 0 {
+		return append(moveList, NewMove(uint16(pieceIndex), uint16(targetIndex)))
+	}
+
+	return append(moveList,
+		NewPromotionMove(uint16(pieceIndex), uint16(targetIndex), PromotionQueen),
+		NewPromotionMove(uint16(pieceIndex), uint16(targetIndex), PromotionRook),
+		NewPromotionMove(uint16(pieceIndex), uint16(targetIndex), PromotionBishop),
+		NewPromotionMove(uint16(pieceIndex), uint16(targetIndex), PromotionKnight))
+}
+
+func (b *Board) generateKnightMoves(moveList []Move, sourceMask uint64, _ uint64) []Move {
+	pieceSet := b.knights & sourceMask
+
+	var pieceIndex int
+	var targetIndex int
+
+	// For each piece
+	for bitScanReverse(&pieceIndex, pieceSet) {
+		pieceSet ^= 1 << pieceIndex
+
+		// For each potential target square
+		targetSquares := pieceMoveMasks.KnightMoveMask[pieceIndex]
+		for bitScanReverse(&targetIndex, targetSquares) {
+			targetSquares ^= 1 << targetIndex
+
+			// Move can be to all squares unless occupied by our own pieces
+			if (1<<targetIndex)&sourceMask == 0 {
+				moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(targetIndex)))
+			}
+		}
+	}
+
+	return moveList
+}
+
+// generateSlidingMoves adds moves for bishops, rooks and queens, using the attack function for the
+// way in which the pieces move
+func (b *Board) generateSlidingMoves(moveList []Move, pieces uint64, sourceMask uint64, targetMask uint64, attacks func(int, uint64) uint64) []Move {
+	pieceSet := pieces & sourceMask
+	occupied := sourceMask | targetMask
+
+	var pieceIndex int
+	var targetIndex int
+
+	// For each piece
+	for bitScanReverse(&pieceIndex, pieceSet) {
+		pieceSet ^= 1 << pieceIndex
+
+		// Move can be to all squares it attacks unless occupied by our own pieces
+		targetSquares := attacks(pieceIndex, occupied) &^ sourceMask
+		for bitScanReverse(&targetIndex, targetSquares) {
+			targetSquares ^= 1 << targetIndex
+
+			moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(targetIndex)))
+		}
+	}
+
+	return moveList
+}
+
+func (b *Board) generateKingMoves(moveList []Move, sourceMask uint64, targetMask uint64) []Move {
+	pieceSet := b.kings & sourceMask
+	anyPiece := sourceMask | targetMask
+	white := b.IsWhiteToMove()
+
+	var pieceIndex int
+	var targetIndex int
+
+	// For each piece
+	for bitScanReverse(&pieceIndex, pieceSet) {
+		pieceSet ^= 1 << pieceIndex
+
+		// For each potential target square
+		targetSquares := pieceMoveMasks.KingMoveMask[pieceIndex]
+		for bitScanReverse(&targetIndex, targetSquares) {
+			targetSquares ^= 1 << targetIndex
+
+			// Move can be to all squares unless occupied by our own pieces
+			if (1<<targetIndex)&sourceMask == 0 {
+				moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(targetIndex)))
+			}
+		}
+
+		// Castling needs the right to castle, nothing between king and rook, and the king must not
+		// be in check or pass through an attacked square. The eligibility patterns check that the
+		// king and rook squares are occupied and those between them are empty.
+		if white {
+			if pieceIndex == 4 { // K on e1
+				if b.canCastleWK() && anyPiece&WhiteKingsideCastlingEligibilityMask == WhiteKingsideCastlingEligibilityPattern {
+					if !b.anySquareAttacked(!white, 4, 5, 6) {
+						moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(pieceIndex+2)))
+					}
+				}
+
+				if b.canCastleWQ() && anyPiece&WhiteQueensideCastlingEligibilityMask == WhiteQueensideCastlingEligibilityPattern {
+					if !b.anySquareAttacked(!white, 4, 3, 2) {
+						moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(pieceIndex-2)))
+					}
+				}
+			}
+		} else {
+			if pieceIndex == 60 { // K on e8
+				if b.canCastleBK() && anyPiece&BlackKingsideCastlingEligibilityMask == BlackKingsideCastlingEligibilityPattern {
+					if !b.anySquareAttacked(!white, 60, 61, 62) {
+						moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(pieceIndex+2)))
+					}
+				}
+
+				if b.canCastleBQ() && anyPiece&BlackQueensideCastlingEligibilityMask == BlackQueensideCastlingEligibilityPattern {
+					if !b.anySquareAttacked(!white, 60, 59, 58) {
+						moveList = append(moveList, NewMove(uint16(pieceIndex), uint16(pieceIndex-2)))
+					}
+				}
+			}
+		}
+	}
+
+	return moveList
+}
+
+// isSquareAttacked reports whether any piece of the given color attacks the square
+func (b *Board) isSquareAttacked(squareIndex int, byWhite bool) bool {
+	attackers := utility.If(byWhite, b.whitePieces, b.blackPieces)
+	occupied := b.whitePieces | b.blackPieces
+
+	// Look outwards from the square as if it held each type of piece in turn. A pawn on the square
+	// would capture in the opposite direction to the attacking pawns
+	pawnMask := utility.If(byWhite, pieceMoveMasks.BlackPawnCaptureMask[squareIndex], pieceMoveMasks.WhitePawnCaptureMask[squareIndex])
+
+	return pawnMask&b.pawns&attackers != 0 ||
+		pieceMoveMasks.KnightMoveMask[squareIndex]&b.knights&attackers != 0 ||
+		pieceMoveMasks.KingMoveMask[squareIndex]&b.kings&attackers != 0 ||
+		BishopAttacks(squareIndex, occupied)&(b.bishops|b.queens)&attackers != 0 ||
+		RookAttacks(squareIndex, occupied)&(b.rooks|b.queens)&attackers != 0
+}
+
+// AttackersTo returns the pieces of either color that attack the square, as if only the squares in
+// occupied held pieces. Removing pieces from occupied reveals those that attack through them.
+func (b *Board) AttackersTo(squareIndex int, occupied uint64) uint64 {
+	// A white pawn attacks the square from where a black pawn on it would capture, and vice versa
+	pawns := pieceMoveMasks.BlackPawnCaptureMask[squareIndex]&b.whitePieces | pieceMoveMasks.WhitePawnCaptureMask[squareIndex]&b.blackPieces
+
+	return pawns&b.pawns |
+		pieceMoveMasks.KnightMoveMask[squareIndex]&b.knights |
+		pieceMoveMasks.KingMoveMask[squareIndex]&b.kings |
+		BishopAttacks(squareIndex, occupied)&(b.bishops|b.queens) |
+		RookAttacks(squareIndex, occupied)&(b.rooks|b.queens)
+}
+
+// anySquareAttacked reports whether any of the squares is attacked by a piece of the given color
+func (b *Board) anySquareAttacked(byWhite bool, squareIndices ...int) bool {
+	for _, squareIndex := range squareIndices {
+		if b.isSquareAttacked(squareIndex, byWhite) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsInCheck reports whether the side to move is in check
+func (b *Board) IsInCheck() bool {
+	white := b.IsWhiteToMove()
+	return b.isSquareAttacked(b.KingIndex(white), !white)
+}
+
+// KingIndex returns the square of the king of the given color
+func (b *Board) KingIndex(white bool) int {
+	var index int
+	bitScanForward(&index, b.kings&utility.If(white, b.whitePieces, b.blackPieces))
+	return index
+}
+
+// IsWhiteToMove reports whether it is white's turn to move
+func (b *Board) IsWhiteToMove() bool {
+	return b.gameState&whiteMask == whiteMask
+}
+
+// PieceAt returns the type of piece on a square, and whether it is white, or NoPiece if the square is empty
+func (b *Board) PieceAt(squareIndex int) (int, bool) {
+	bitboardBit := uint64(1) << squareIndex
+	white := b.whitePieces&bitboardBit != 0
+
+	switch {
+	case b.pawns&bitboardBit != 0:
+		return Pawn, white
+	case b.knights&bitboardBit != 0:
+		return Knight, white
+	case b.bishops&bitboardBit != 0:
+		return Bishop, white
+	case b.rooks&bitboardBit != 0:
+		return Rook, white
+	case b.queens&bitboardBit != 0:
+		return Queen, white
+	case b.kings&bitboardBit != 0:
+		return King, white
+	}
+
+	return NoPiece, false
+}
+
+// Pieces returns the bitboard of the pieces of the given type and color
+func (b *Board) Pieces(pieceType int, white bool) uint64 {
+	return *b.pieceBitboard(pieceType) & b.ColorPieces(white)
+}
+
+// ColorPieces returns the bitboard of all the pieces of the given color
+func (b *Board) ColorPieces(white bool) uint64 {
+	return utility.If(white, b.whitePieces, b.blackPieces)
+}
+
+// Occupied returns the bitboard of all the pieces on the board
+func (b *Board) Occupied() uint64 {
+	return b.whitePieces | b.blackPieces
+}
+
+// HalfMoveClock returns the number of half moves since the last capture or pawn move
+func (b *Board) HalfMoveClock() int {
+	return int(b.getHalfMoveClock())
+}
+
+// FullMoveNumber returns the number of the move being played, which starts at 1 and increases after black moves
+func (b *Board) FullMoveNumber() int {
+	return int(b.getFullMoveNumber())
+}
+
+// pieceBitboard returns the bitboard that holds pieces of the given type
+func (b *Board) pieceBitboard(pieceType int) *uint64 {
+	switch pieceType {
+	case Pawn:
+		return &b.pawns
+	case Knight:
+		return &b.knights
+	case Bishop:
+		return &b.bishops
+	case Rook:
+		return &b.rooks
+	case Queen:
+		return &b.queens
+	}
+
+	return &b.kings
+}
+
+// GetHash returns the Zobrist hash of the position
+func (b *Board) GetHash() uint64 {
+	return b.hash
+}
+
+// computeHash calculates the Zobrist hash of the position from scratch
+func (b *Board) computeHash() uint64 {
+	var hash uint64
+
+	for squareIndex := 0; squareIndex < 64; squareIndex++ {
+		pieceType, white := b.PieceAt(squareIndex)
+		if pieceType != NoPiece {
+			hash ^= zobristPieceKeys[utility.If(white, 0, 1)][pieceType][squareIndex]
+		}
+	}
+
+	return hash ^ b.stateHash()
+}
+
+// stateHash returns the part of the hash that depends on the game state rather than the pieces
+func (b *Board) stateHash() uint64 {
+	hash := zobristCastlingKeys[(b.gameState&(castlingMask_WK|castlingMask_WQ|castlingMask_BK|castlingMask_BQ))>>2]
+
+	if b.getEnPassantIndex() != 0 {
+		hash ^= zobristEnPassantKeys[b.getEnPassantIndex()%8]
+	}
+
+	if !b.IsWhiteToMove() {
+		hash ^= zobristBlackToMoveKey
+	}
+
+	return hash
+}
+
+// MakeMove plays a legal (or pseudo-legal) move on the board, returning a copy of the board
+// as it was before the move so that the move can be taken back with UnmakeMove
+func (b *Board) MakeMove(move Move) *Board {
+	// Copy the board state
+	backupBoard := *b
+
+	from := int(move.From())
+	to := int(move.To())
+	fromBit := uint64(1) << from
+	toBit := uint64(1) << to
+
+	white := b.IsWhiteToMove()
+	colorIndex := utility.If(white, 0, 1)
+	ownPieces := utility.If(white, &b.whitePieces, &b.blackPieces)
+	opponentPieces := utility.If(white, &b.blackPieces, &b.whitePieces)
+
+	// Take the state out of the hash, to be put back once it has been updated
+	hash := b.hash ^ b.stateHash()
+
+	pieceType, _ := b.PieceAt(from)
+	capturedType, _ := b.PieceAt(to)
+
+	// Any capture removes the piece from the target square
+	if capturedType != NoPiece {
+		*b.pieceBitboard(capturedType) &^= toBit
+		*opponentPieces &^= toBit
+		hash ^= zobristPieceKeys[1-colorIndex][capturedType][to]
+	}
+
+	// Move the piece
+	*b.pieceBitboard(pieceType) ^= fromBit | toBit
+	*ownPieces ^= fromBit | toBit
+	hash ^= zobristPieceKeys[colorIndex][pieceType][from] ^ zobristPieceKeys[colorIndex][pieceType][to]
+
+	enPassantIndex := int(b.getEnPassantIndex())
+	b.clearEnPassantIndex()
+
+	switch pieceType {
+	case Pawn:
+		direction := utility.If(white, 8, -8)
+
+		// En passant captures the pawn that double-slid past the target square
+		if to == enPassantIndex && enPassantIndex != 0 {
+			capturedIndex := to - direction
+			b.pawns &^= 1 << capturedIndex
+			*opponentPieces &^= 1 << capturedIndex
+			hash ^= zobristPieceKeys[1-colorIndex][Pawn][capturedIndex]
+		}
+
+		if move.IsPromotion() {
+			promotedType := move.PromotionPieceType()
+			b.pawns &^= toBit
+			*b.pieceBitboard(promotedType) |= toBit
+			hash ^= zobristPieceKeys[colorIndex][Pawn][to] ^ zobristPieceKeys[colorIndex][promotedType][to]
+		}
+
+		// A double slide creates an en passant square, but only if an opposing pawn could use it
+		if to-from == 2*direction {
+			passedIndex := from + direction
+			capturers := utility.If(white, pieceMoveMasks.WhitePawnCaptureMask[passedIndex], pieceMoveMasks.BlackPawnCaptureMask[passedIndex])
+			if capturers&b.pawns&*opponentPieces != 0 {
+				b.setEnPassantIndex(uint32(passedIndex))
+			}
+		}
+
+	case King:
+		// Castling also moves the rook
+		if to-from == 2 || from-to == 2 {
+			rookFrom := utility.If(to > from, from+3, from-4)
+			rookTo := (from + to) / 2
+			rookBits := uint64(1)<<rookFrom | uint64(1)<<rookTo
+			b.rooks ^= rookBits
+			*ownPieces ^= rookBits
+			hash ^= zobristPieceKeys[colorIndex][Rook][rookFrom] ^ zobristPieceKeys[colorIndex][Rook][rookTo]
+		}
+	}
+
+	// Moving the king or a rook, or capturing a rook, loses castling rights
+	b.gameState &^= castlingRightsLost[from] | castlingRightsLost[to]
+
+	// Clocks
+	if pieceType == Pawn || capturedType != NoPiece {
+		b.setHalfMoveClock(0)
+	} else {
+		b.incrementHalfMoveClock()
+	}
+
+	if !white {
+		b.incrementFullMoveNumber()
+	}
+
+	// Switch sides
+	b.gameState ^= whiteMask | blackMask
+
+	b.hash = hash ^ b.stateHash()
+
+	return &backupBoard
+}
+
+// castlingRightsLost holds, for each square, the castling rights lost when a move starts or ends there
+var castlingRightsLost = func() [64]uint32 {
+	var lost [64]uint32
+	lost[0] = castlingMask_WQ
+	lost[4] = castlingMask_WK | castlingMask_WQ
+	lost[7] = castlingMask_WK
+	lost[56] = castlingMask_BQ
+	lost[60] = castlingMask_BK | castlingMask_BQ
+	lost[63] = castlingMask_BK
+	return lost
+}()
+
+// UnmakeMove takes back a move, restoring the board returned by MakeMove
+func (b *Board) UnmakeMove(backupBoard *Board) {
+	// Restore the board state
+	*b = *backupBoard
+}
+
+// MakeNullMove passes the turn to the opponent without moving a piece, returning a copy of the
+// board as it was so that it can be restored with UnmakeMove
+func (b *Board) MakeNullMove() *Board {
+	backupBoard := *b
+
+	hash := b.hash ^ b.stateHash()
+	b.clearEnPassantIndex()
+	b.gameState ^= whiteMask | blackMask
+	b.hash = hash ^ b.stateHash()
+
+	return &backupBoard
+}
+
+func (b *Board) getFullMoveNumber() uint32 {
+	return (b.gameState & fullMoveMask) >> fullMoveShift
+}
+
+func (b *Board) setFullMoveNumber(number uint32) {
+	// Mask out the current value, shift the new number, and it to size and or it back into the state
+	// The and-to-size step should be unnecessary, but at least means than it prevents a rogue value impacting
+	// any other state bits
+	b.gameState = (b.gameState & fullMoveXOR) | ((number << fullMoveShift) & fullMoveMask)
+}
+
+func (b *Board) incrementFullMoveNumber() {
+	// Increment the number in situ
+	b.gameState = (b.gameState & fullMoveXOR) | (((b.gameState & fullMoveMask) + fullMoveLSB) & fullMoveMask)
+}
+
+func (b *Board) getHalfMoveClock() uint32 {
+	return (b.gameState & halfMoveMask) >> halfMoveShift
+}
+
+func (b *Board) setHalfMoveClock(number uint32) {
+	// Mask out the current value, shift the new number, and it to size and or it back into the state
+	// The and-to-size step should be unnecessary, but at least means than it prevents a rogue value impacting
+	// any other state bits
+	b.gameState = (b.gameState & halfMoveXOR) | ((number << halfMoveShift) & halfMoveMask)
+}
+
+func (b *Board) incrementHalfMoveClock() {
+	b.gameState = (b.gameState & halfMoveXOR) | ((b.gameState & halfMoveMask) + halfMoveLSB)
+}
+
+func (b *Board) getEnPassantIndex() uint32 {
+	return (b.gameState & enPassantMask) >> enPassantShift
+}
+
+func (b *Board) setEnPassantIndex(index uint32) {
+	b.gameState = (b.gameState & enPassantXOR) | (index << enPassantShift)
+}
+
+func (b *Board) clearEnPassantIndex() {
+	b.gameState = (b.gameState & enPassantXOR)
+}
+
+func (b *Board) canCastleWK() bool {
+	return (b.gameState & castlingMask_WK) != 0
+}
+
+func (b *Board) canCastleWQ() bool {
+	return (b.gameState & castlingMask_WQ) != 0
+}
+
+func (b *Board) canCastleBK() bool {
+	return (b.gameState & castlingMask_BK) != 0
+}
+
+func (b *Board) canCastleBQ() bool {
+	return (b.gameState & castlingMask_BQ) != 0
+}
+
+// Unicode chess symbols for each piece type, white and then black
+var unicodePieces = [2][PieceTypeCount]string{
+	{"♙", "♘", "♗", "♖", "♕", "♔"},
+	{"♟", "♞", "♝", "♜", "♛", "♚"},
+}
+
+// Draw returns a picture of the board from white's side, with its state, as lines of text
+func (b *Board) Draw(useUnicode bool) []string {
+	const separator = "   +---+---+---+---+---+---+---+---+"
+
+	lines := []string{separator}
+
+	for rank := 7; rank >= 0; rank-- {
+		var line strings.Builder
+		fmt.Fprintf(&line, " %d |", rank+1)
+
+		for file := 0; file < 8; file++ {
+			pieceType, white := b.PieceAt(rank*8 + file)
+
+			piece := " "
+			if pieceType != NoPiece {
+				if useUnicode {
+					piece = unicodePieces[utility.If(white, 0, 1)][pieceType]
+				} else {
+					piece = string(pieceLetter(pieceType, white))
+				}
+			}
+
+			fmt.Fprintf(&line, " %s |", piece)
+		}
+
+		lines = append(lines, line.String(), separator)
+	}
+
+	lines = append(lines, "     a   b   c   d   e   f   g   h", "")
+
+	castling := ""
+	if b.canCastleWK() {
+		castling += "K"
+	}
+	if b.canCastleWQ() {
+		castling += "Q"
+	}
+	if b.canCastleBK() {
+		castling += "k"
+	}
+	if b.canCastleBQ() {
+		castling += "q"
+	}
+
+	enPassant := "[none]"
+	if b.getEnPassantIndex() != 0 {
+		enPassant = indexToSquare(b.getEnPassantIndex())
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("FEN:               %s", b.ToFen()),
+		fmt.Sprintf("Hash:              %016x", b.hash),
+		fmt.Sprintf("To play:           %s", utility.If(b.IsWhiteToMove(), "White", "Black")),
+		fmt.Sprintf("Castling rights:   %s", utility.If(castling == "", "[none]", castling)),
+		fmt.Sprintf("En passant square: %s", enPassant),
+		fmt.Sprintf("Half move clock:   %d", b.getHalfMoveClock()),
+		fmt.Sprintf("Full move number:  %d", b.getFullMoveNumber()),
+	)
+
+	switch status := b.Status(); {
+	case status != InProgress:
+		lines = append(lines, "Status:            "+status.String())
+	case b.IsInCheck():
+		lines = append(lines, "Status:            Check")
+	}
+
+	return lines
+}
+
+// DrawMasks returns the board's bitboards, and its state, as lines of text
+func (b *Board) DrawMasks() []string {
+	return []string{
+		fmt.Sprintf("Pawns:             %064b", b.pawns),
+		fmt.Sprintf("Knights:           %064b", b.knights),
+		fmt.Sprintf("Bishops:           %064b", b.bishops),
+		fmt.Sprintf("Rooks:             %064b", b.rooks),
+		fmt.Sprintf("Queens:            %064b", b.queens),
+		fmt.Sprintf("Kings:             %064b", b.kings),
+		fmt.Sprintf("White:             %064b", b.whitePieces),
+		fmt.Sprintf("Black:             %064b", b.blackPieces),
+		fmt.Sprintf("All:               %064b", b.whitePieces|b.blackPieces),
+		"",
+		fmt.Sprintf("Game State:        %032b", b.gameState),
+		fmt.Sprintf("Color To Play:     %032b", b.gameState&(whiteMask|blackMask)),
+		fmt.Sprintf("Castling Rights:   %032b", b.gameState&(castlingMask_WK|castlingMask_WQ|castlingMask_BK|castlingMask_BQ)),
+		fmt.Sprintf("En Passant Square: %032b", b.gameState&enPassantMask),
+		fmt.Sprintf("Half Move Clock:   %032b", b.gameState&halfMoveMask),
+		fmt.Sprintf("Full Move Number:  %032b", b.gameState&fullMoveMask),
+	}
+}
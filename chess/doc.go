@@ -0,0 +1,38 @@
+// Package chess holds positions and moves: reading and writing FEN, legal move generation, UCI and
+// Standard Algebraic Notation, Zobrist hashing and whether the game is over. It knows nothing of
+// engines or protocols, so it can be used on its own.
+//
+// Squares are numbered from 0 for a1 to 63 for h8, rank by rank, and bitboards use the same order
+// with a1 as the least significant bit.
+//
+// Setting up a position and listing its legal moves:
+//
+//	board, err := chess.NewBoard(chess.FenStartingPosition)
+//	if err != nil {
+//		return err
+//	}
+//
+//	moveList, _ := board.GetMoves(make([]chess.Move, 0, 256))
+//	for _, move := range moveList {
+//		fmt.Println(move.ToUciString(), board.ToSan(move))
+//	}
+//
+// Playing moves given in UCI notation, then describing the result:
+//
+//	for _, text := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+//		move, err := board.FindMove(text)
+//		if err != nil {
+//			return err
+//		}
+//		board.MakeMove(move)
+//	}
+//
+//	fmt.Println(board.ToFen())  // rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3
+//	fmt.Println(board.Status()) // Checkmate
+//
+// MakeMove returns the board as it was, so that searches can take a move back cheaply:
+//
+//	undo := board.MakeMove(move)
+//	nodes, _ := chess.Perft(board, 3)
+//	board.UnmakeMove(undo)
+package chess
@@ -0,0 +1,106 @@
+package chess_test
+
+import (
+	"fmt"
+
+	// Internal references
+	"goche/chess"
+)
+
+func ExampleNewBoard() {
+	board, err := chess.NewBoard("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	moveList, _ := board.GetMoves(make([]chess.Move, 0, 256))
+	fmt.Println(len(moveList), "legal moves")
+	// Output:
+	// 27 legal moves
+}
+
+func ExampleBoard_FindMove() {
+	board, _ := chess.NewBoard(chess.FenStartingPosition)
+
+	for _, text := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		move, err := board.FindMove(text)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		board.MakeMove(move)
+	}
+
+	fmt.Println(board.ToFen())
+	fmt.Println(board.Status())
+	// Output:
+	// rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3
+	// Checkmate
+}
+
+func ExampleBoard_ToSan() {
+	board, _ := chess.NewBoard("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+
+	for _, text := range []string{"f1b5", "f3e5", "d2d4"} {
+		move, _ := board.FindMove(text)
+		fmt.Println(text, board.ToSan(move))
+	}
+	// Output:
+	// f1b5 Bb5
+	// f3e5 Nxe5
+	// d2d4 d4
+}
+
+func ExampleBoard_MakeMove() {
+	board, _ := chess.NewBoard(chess.FenStartingPosition)
+	move, _ := board.FindMove("e2e4")
+
+	undo := board.MakeMove(move)
+	fmt.Println(board.ToFen())
+
+	board.UnmakeMove(undo)
+	fmt.Println(board.ToFen())
+	// Output:
+	// rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1
+	// rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
+}
+
+func ExampleBoard_Status() {
+	for _, fen := range []string{
+		chess.FenStartingPosition,
+		"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+		"7k/8/6K1/8/8/8/8/R7 w - - 100 80",
+		"b7/8/4k3/8/8/3B4/4K3/8 w - - 0 1",
+	} {
+		board, _ := chess.NewBoard(fen)
+		fmt.Println(board.Status())
+	}
+	// Output:
+	// In progress
+	// Stalemate
+	// Fifty move rule
+	// Insufficient material
+}
+
+func ExamplePerft() {
+	board, _ := chess.NewBoard(chess.FenStartingPosition)
+
+	for depth := 1; depth <= 3; depth++ {
+		nodes, _ := chess.Perft(board, depth)
+		fmt.Println(depth, nodes)
+	}
+	// Output:
+	// 1 20
+	// 2 400
+	// 3 8902
+}
+
+func ExampleParseSquare() {
+	index, _ := chess.ParseSquare("e4")
+	fmt.Println(index, chess.SquareName(index))
+	fmt.Printf("%016x\n", chess.KnightAttacks(index))
+	// Output:
+	// 28 e4
+	// 0000284400442800
+}
@@ -0,0 +1,85 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FenStartingPosition is the position at the start of a game
+const FenStartingPosition = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// The letters used for each piece type in FEN, in upper case for white and lower case for black
+const pieceLetters = "PNBRQK"
+
+// ToFen returns the position as a FEN string
+func (b *Board) ToFen() string {
+	var builder strings.Builder
+
+	// Piece placement starts on the 8th rank, 1st file
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			pieceType, white := b.PieceAt(rank*8 + file)
+			if pieceType == NoPiece {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				fmt.Fprintf(&builder, "%d", empty)
+				empty = 0
+			}
+			builder.WriteByte(pieceLetter(pieceType, white))
+		}
+
+		if empty > 0 {
+			fmt.Fprintf(&builder, "%d", empty)
+		}
+		if rank > 0 {
+			builder.WriteByte('/')
+		}
+	}
+
+	if b.IsWhiteToMove() {
+		builder.WriteString(" w ")
+	} else {
+		builder.WriteString(" b ")
+	}
+
+	castling := ""
+	if b.canCastleWK() {
+		castling += "K"
+	}
+	if b.canCastleWQ() {
+		castling += "Q"
+	}
+	if b.canCastleBK() {
+		castling += "k"
+	}
+	if b.canCastleBQ() {
+		castling += "q"
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	builder.WriteString(castling)
+
+	if b.getEnPassantIndex() != 0 {
+		fmt.Fprintf(&builder, " %s", indexToSquare(b.getEnPassantIndex()))
+	} else {
+		builder.WriteString(" -")
+	}
+
+	fmt.Fprintf(&builder, " %d %d", b.getHalfMoveClock(), b.getFullMoveNumber())
+
+	return builder.String()
+}
+
+// pieceLetter returns the FEN letter for a piece
+func pieceLetter(pieceType int, white bool) byte {
+	letter := pieceLetters[pieceType]
+	if !white {
+		letter += 'a' - 'A'
+	}
+	return letter
+}
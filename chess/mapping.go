@@ -0,0 +1,90 @@
+package chess
+
+import (
+	"fmt"
+
+	"math/bits"
+)
+
+/*
+func squareToIndex(square string) uint8 {
+	return uint8((square[0] - 'a') + ((square[1] - '1') * 8))
+}
+
+func squareToIndex32(square string) uint32 {
+	return uint32((square[0] - 'a') + ((square[1] - '1') * 8))
+}
+
+func indexToSquare(index uint8) string {
+	return fmt.Sprintf("%c%c", 'a'+(index%8), '1'+(index/8))
+}
+
+func index32ToSquare(index uint32) string {
+	return fmt.Sprintf("%c%c", 'a'+(index%8), '1'+(index/8))
+}
+*/
+
+// indexNumber is any of the integer types that square indices are held in
+type indexNumber interface {
+	uint8 | uint32 | uint64
+}
+
+func squareToIndex[numberType indexNumber](square string) numberType {
+	return rankFileToIndex[numberType](square[0]-'a', square[1]-'1')
+	//return numberType((square[0] - 'a') + ((square[1] - '1') * 8))
+}
+
+// ParseSquare returns the index of a square named in algebraic notation, from 0 for a1 to 63 for h8
+func ParseSquare(text string) (int, error) {
+	if !isSquareString(text) {
+		return 0, fmt.Errorf("bad square: %s", text)
+	}
+	return int(squareToIndex[uint8](text)), nil
+}
+
+// SquareName returns the name of the square with the given index (e.g. e4)
+func SquareName(squareIndex int) string {
+	return indexToSquare(uint8(squareIndex))
+}
+
+// isSquareString reports whether the text names a square, such as e4
+func isSquareString(text string) bool {
+	return len(text) == 2 && text[0] >= 'a' && text[0] <= 'h' && text[1] >= '1' && text[1] <= '8'
+}
+
+func rankFileToIndex[numberType indexNumber](file byte, rank byte) numberType {
+	return numberType(file + rank*8)
+}
+
+func indexToSquare[numberType indexNumber](index numberType) string {
+	return fmt.Sprintf("%c%c", 'a'+(index%8), '1'+(index/8))
+}
+
+func indexToBitboard[numberType indexNumber](index numberType) uint64 {
+	return 1 << index
+}
+
+/*
+func bitboardToIndex[numberType indexNumber](bitboard uint64) numberType {
+	// TODO get this from a lookup table or a bitshift
+	return 0
+}
+*/
+
+func bitScanForward(index *int, mask uint64) bool {
+	if mask == 0 {
+		return false
+	}
+
+	*index = bits.TrailingZeros64(mask)
+	return true
+}
+
+func bitScanReverse(index *int, mask uint64) bool {
+	if mask == 0 {
+		return false
+	}
+
+	*index = bits.Len64(mask) - 1
+	return true
+}
@@ -0,0 +1,82 @@
+package chess
+
+import "fmt"
+
+// Move requires:
+// - from square (6 bits for index)
+// - to square (6 bits for index)
+// - promotion piece (2 bits for knight, bishop, rook, queen)
+// - promotion flag (1 bit, as a knight promotion would otherwise look like no promotion)
+// - 1 bit spare, as castling and en passant can be recognised from the board
+type Move uint16
+
+// Promotion pieces, as held in a Move
+const (
+	PromotionKnight uint16 = iota
+	PromotionBishop
+	PromotionRook
+	PromotionQueen
+)
+
+const (
+	promotionShift = 12
+	promotionMask  = 0b11 << promotionShift
+	promotionFlag  = 1 << 14
+)
+
+// The text UCI uses for the absence of a move
+const NullMoveString = "0000"
+
+// NewPromotionMove creates a move of a pawn to the last rank, given the square indices and one of
+// PromotionKnight, PromotionBishop, PromotionRook or PromotionQueen
+func NewPromotionMove(from, to uint16, promotionPiece uint16) Move {
+	return Move(from | (to << 6) | (promotionPiece << promotionShift) | promotionFlag)
+}
+
+// NewMove creates a move between two square indices
+func NewMove(from, to uint16) Move {
+	return Move(from | (to << 6))
+}
+
+// From returns the index of the square the move starts on
+func (m Move) From() uint8 {
+	return uint8(m & 0b111111)
+}
+
+// To returns the index of the square the move ends on
+func (m Move) To() uint8 {
+	return uint8((m >> 6) & 0b111111)
+}
+
+// IsPromotion reports whether the move promotes a pawn
+func (m Move) IsPromotion() bool {
+	return m&promotionFlag != 0
+}
+
+// PromotionPiece returns the promotion piece, one of PromotionKnight, PromotionBishop, PromotionRook or PromotionQueen
+func (m Move) PromotionPiece() uint16 {
+	return uint16(m&promotionMask) >> promotionShift
+}
+
+// PromotionPieceType returns the type of piece a pawn promotes to, e.g. Queen
+func (m Move) PromotionPieceType() int {
+	return Knight + int(m.PromotionPiece())
+}
+
+// ToString returns the move's bits and its UCI notation, for debugging
+func (m Move) ToString() string {
+	return fmt.Sprintf("%016b %s", m, m.ToUciString())
+}
+
+// ToUciString returns the move in the long algebraic notation used by UCI (e.g. e2e4, e7e8q)
+func (m Move) ToUciString() string {
+	from := m.From()
+	to := m.To()
+
+	text := fmt.Sprintf("%c%c%c%c", 'a'+from%8, '1'+from/8, 'a'+to%8, '1'+to/8)
+	if m.IsPromotion() {
+		text += string("nbrq"[m.PromotionPiece()])
+	}
+
+	return text
+}
@@ -0,0 +1,153 @@
+package chess
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	// Internal references
+	"goche/utility"
+)
+
+// ToSan returns a legal move in Standard Algebraic Notation (e.g. Nf3, exd5, O-O, e8=Q+)
+func (b *Board) ToSan(move Move) string {
+	from := int(move.From())
+	to := int(move.To())
+	pieceType, _ := b.PieceAt(from)
+	capturedType, _ := b.PieceAt(to)
+
+	var san strings.Builder
+
+	switch {
+	case pieceType == King && (to-from == 2 || from-to == 2):
+		if to > from {
+			san.WriteString("O-O")
+		} else {
+			san.WriteString("O-O-O")
+		}
+
+	case pieceType == Pawn:
+		if from%8 != to%8 {
+			// Pawns only change file when capturing, including en passant
+			fmt.Fprintf(&san, "%cx", 'a'+from%8)
+		}
+		san.WriteString(indexToSquare(uint8(to)))
+
+		if move.IsPromotion() {
+			fmt.Fprintf(&san, "=%c", pieceLetters[move.PromotionPieceType()])
+		}
+
+	default:
+		san.WriteByte(pieceLetters[pieceType])
+		san.WriteString(b.disambiguation(move, pieceType))
+		if capturedType != NoPiece {
+			san.WriteByte('x')
+		}
+		san.WriteString(indexToSquare(uint8(to)))
+	}
+
+	// Check and checkmate
+	undo := b.MakeMove(move)
+	if b.IsInCheck() {
+		replies, _ := b.GetMoves(make([]Move, 0, 256))
+		if len(replies) == 0 {
+			san.WriteByte('#')
+		} else {
+			san.WriteByte('+')
+		}
+	}
+	b.UnmakeMove(undo)
+
+	return san.String()
+}
+
+// disambiguation returns the file, rank or square needed to tell a move apart from other legal moves
+// by the same type of piece to the same square, as in Nbd2, R1e2 or Qh4e1
+func (b *Board) disambiguation(move Move, pieceType int) string {
+	from := int(move.From())
+	to := int(move.To())
+
+	moveList, _ := b.GetMoves(make([]Move, 0, 256))
+
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range moveList {
+		otherFrom := int(other.From())
+		if otherFrom == from || int(other.To()) != to {
+			continue
+		}
+
+		if otherType, _ := b.PieceAt(otherFrom); otherType != pieceType {
+			continue
+		}
+
+		ambiguous = true
+		sameFile = sameFile || otherFrom%8 == from%8
+		sameRank = sameRank || otherFrom/8 == from/8
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(rune('a' + from%8))
+	case !sameRank:
+		return string(rune('1' + from/8))
+	}
+
+	return indexToSquare(uint8(from))
+}
+
+// FindMove returns the legal move described in long algebraic notation (e.g. e2e4, e7e8q)
+func (b *Board) FindMove(text string) (Move, error) {
+	moveList, err := b.GetMoves(make([]Move, 0, 256))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, move := range moveList {
+		if move.ToUciString() == text {
+			return move, nil
+		}
+	}
+
+	return 0, fmt.Errorf("illegal move: %s", text)
+}
+
+// Mirror returns a copy of the position with the board flipped vertically and the colors swapped,
+// so that the side to move is the other color but the position is otherwise the same
+func (b *Board) Mirror() *Board {
+	mirrored := &Board{
+		whitePieces: bits.ReverseBytes64(b.blackPieces),
+		blackPieces: bits.ReverseBytes64(b.whitePieces),
+		pawns:       bits.ReverseBytes64(b.pawns),
+		knights:     bits.ReverseBytes64(b.knights),
+		bishops:     bits.ReverseBytes64(b.bishops),
+		rooks:       bits.ReverseBytes64(b.rooks),
+		queens:      bits.ReverseBytes64(b.queens),
+		kings:       bits.ReverseBytes64(b.kings),
+	}
+
+	mirrored.gameState = b.gameState & (fullMoveMask | halfMoveMask)
+	mirrored.gameState |= utility.If(b.IsWhiteToMove(), blackMask, whiteMask)
+
+	if b.canCastleWK() {
+		mirrored.gameState |= castlingMask_BK
+	}
+	if b.canCastleWQ() {
+		mirrored.gameState |= castlingMask_BQ
+	}
+	if b.canCastleBK() {
+		mirrored.gameState |= castlingMask_WK
+	}
+	if b.canCastleBQ() {
+		mirrored.gameState |= castlingMask_WQ
+	}
+
+	if b.getEnPassantIndex() != 0 {
+		mirrored.setEnPassantIndex(b.getEnPassantIndex() ^ 56)
+	}
+
+	mirrored.hash = mirrored.computeHash()
+
+	return mirrored
+}
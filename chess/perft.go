@@ -0,0 +1,61 @@
+package chess
+
+import "fmt"
+
+// Perft counts the positions reached at the given depth from the board, for verifying move generation
+func Perft(board *Board, depth int) (int, error) {
+	if depth == 0 {
+		return 1, nil
+	}
+
+	moveList, err := board.GetMoves(make([]Move, 0, 256))
+	if err != nil {
+		return 0, fmt.Errorf("move generation failed: %w", err)
+	}
+
+	// The moves themselves are the positions at the last step, so there is no need to make them
+	if depth == 1 {
+		return len(moveList), nil
+	}
+
+	nodes := 0
+	for _, move := range moveList {
+		undo := board.MakeMove(move)
+		moveNodes, err := Perft(board, depth-1)
+		board.UnmakeMove(undo)
+
+		if err != nil {
+			return 0, err
+		}
+		nodes += moveNodes
+	}
+
+	return nodes, nil
+}
+
+// PerftDivide counts the positions reached at the given depth after each legal move from the board,
+// keyed by the move in UCI notation
+func PerftDivide(board *Board, depth int) (map[string]int, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("depth must be at least 1")
+	}
+
+	moveList, err := board.GetMoves(make([]Move, 0, 256))
+	if err != nil {
+		return nil, fmt.Errorf("move generation failed: %w", err)
+	}
+
+	divided := make(map[string]int, len(moveList))
+	for _, move := range moveList {
+		undo := board.MakeMove(move)
+		nodes, err := Perft(board, depth-1)
+		board.UnmakeMove(undo)
+
+		if err != nil {
+			return nil, err
+		}
+		divided[move.ToUciString()] = nodes
+	}
+
+	return divided, nil
+}
@@ -0,0 +1,227 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+
+	// Internal references
+	"goche/logger"
+)
+
+// PieceMoveMask holds, for each square index, the squares a piece standing there could move to on an
+// empty board
+type PieceMoveMask struct {
+	WhitePawnSlideMask          [64]uint64
+	WhitePawnDoubleSlideMask    [64]uint64
+	WhitePawnCaptureMask        [64]uint64
+	BlackPawnSlideMask          [64]uint64
+	BlackPawnDoubleSlideMask    [64]uint64
+	BlackPawnCaptureMask        [64]uint64
+	DoubleSlideEligiblePawnMask [64]uint64
+	KnightMoveMask              [64]uint64
+	DiagonalMoveMask            [64]uint64
+	StraightMoveMask            [64]uint64
+	QueenMoveMask               [64]uint64
+	KingMoveMask                [64]uint64
+	RayMask                     [directionCount][64]uint64
+}
+
+// Directions for the rays along which bishops, rooks and queens move. Those that step towards
+// higher square indices are marked as positive, as the nearest blocker is then the lowest set bit
+const (
+	North = iota
+	NorthEast
+	East
+	NorthWest
+	South
+	SouthWest
+	West
+	SouthEast
+	directionCount
+)
+
+var directionFileStep = [directionCount]int{0, 1, 1, -1, 0, -1, -1, 1}
+var directionRankStep = [directionCount]int{1, 1, 0, 1, -1, -1, 0, -1}
+
+func isPositiveDirection(direction int) bool {
+	return direction < South
+}
+
+// 64-bit constant masks using this template:
+//
+//	0b0000000000000000000000000000000000000000000000000000000000000000
+const (
+	WhiteSideOfTheBoardMask       = 0b0000000000000000000000000000000011111111111111111111111111111111
+	WhitePawnPromotionMask        = 0b1111111100000000000000000000000000000000000000000000000000000000
+	WhitePawnSlideEligibilityMask = 0b0000000000000000000000000000000000000000000000001111111100000000
+
+	BlackSideOfTheBoardMask       = 0b1111111111111111111111111111111100000000000000000000000000000000
+	BlackPawnPromotionMask        = 0b0000000000000000000000000000000000000000000000000000000011111111
+	BlackPawnSlideEligibilityMask = 0b0000000011111111000000000000000000000000000000000000000000000000
+
+	WhiteKingsideCastlingEligibilityMask  = 0b0000000000000000000000000000000000000000000000000000000011110000
+	WhiteQueensideCastlingEligibilityMask = 0b0000000000000000000000000000000000000000000000000000000000011111
+	BlackKingsideCastlingEligibilityMask  = 0b1111000000000000000000000000000000000000000000000000000000000000
+	BlackQueensideCastlingEligibilityMask = 0b0001111100000000000000000000000000000000000000000000000000000000
+
+	WhiteKingsideCastlingEligibilityPattern  = 0b0000000000000000000000000000000000000000000000000000000010010000
+	WhiteQueensideCastlingEligibilityPattern = 0b0000000000000000000000000000000000000000000000000000000000010001
+	BlackKingsideCastlingEligibilityPattern  = 0b1001000000000000000000000000000000000000000000000000000000000000
+	BlackQueensideCastlingEligibilityPattern = 0b0001000100000000000000000000000000000000000000000000000000000000
+)
+
+// The move masks, calculated when the package is initialised. They are not exported, so that nothing
+// outside the package can change what the move generator relies on.
+var pieceMoveMasks PieceMoveMask
+
+// PieceMoveMasks returns a copy of the move masks
+func PieceMoveMasks() PieceMoveMask {
+	return pieceMoveMasks
+}
+
+func init() {
+	for rankIndex := 0; rankIndex < 8; rankIndex++ {
+		for fileIndex := 0; fileIndex < 8; fileIndex++ {
+			squareIndex := rankIndex*8 + fileIndex
+
+			// Knight moves
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex-2, rankIndex-1)
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex+2, rankIndex-1)
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex-2, rankIndex+1)
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex+2, rankIndex+1)
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex-1, rankIndex-2)
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex+1, rankIndex-2)
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex-1, rankIndex+2)
+			_ = setIfOnBoard(&pieceMoveMasks.KnightMoveMask[squareIndex], fileIndex+1, rankIndex+2)
+
+			// Directional rays for Bishop/Rook/Queen moves - in each direction, go as far as we can and then break
+			for direction := 0; direction < directionCount; direction++ {
+				for d := 1; d < 8; d++ {
+					if !setIfOnBoard(&pieceMoveMasks.RayMask[direction][squareIndex], fileIndex+directionFileStep[direction]*d, rankIndex+directionRankStep[direction]*d) {
+						break
+					}
+				}
+			}
+
+			pieceMoveMasks.StraightMoveMask[squareIndex] = pieceMoveMasks.RayMask[North][squareIndex] | pieceMoveMasks.RayMask[East][squareIndex] |
+				pieceMoveMasks.RayMask[South][squareIndex] | pieceMoveMasks.RayMask[West][squareIndex]
+			pieceMoveMasks.DiagonalMoveMask[squareIndex] = pieceMoveMasks.RayMask[NorthEast][squareIndex] | pieceMoveMasks.RayMask[NorthWest][squareIndex] |
+				pieceMoveMasks.RayMask[SouthEast][squareIndex] | pieceMoveMasks.RayMask[SouthWest][squareIndex]
+			pieceMoveMasks.QueenMoveMask[squareIndex] = pieceMoveMasks.StraightMoveMask[squareIndex] | pieceMoveMasks.DiagonalMoveMask[squareIndex]
+
+			// King moves
+			for r := -1; r <= 1; r++ {
+				for f := -1; f <= 1; f++ {
+					if f == 0 && r == 0 {
+						continue
+					}
+					_ = setIfOnBoard(&pieceMoveMasks.KingMoveMask[squareIndex], fileIndex+f, rankIndex+r)
+				}
+			}
+
+			// Pawn moves - keep capture mask apart from main mask
+
+			// White
+			if rankIndex < 7 {
+				_ = setIfOnBoard(&pieceMoveMasks.WhitePawnSlideMask[squareIndex], fileIndex, rankIndex+1)
+			}
+
+			if rankIndex == 1 {
+				_ = setIfOnBoard(&pieceMoveMasks.WhitePawnDoubleSlideMask[squareIndex], fileIndex, rankIndex+2)
+				_ = setIfOnBoard(&pieceMoveMasks.DoubleSlideEligiblePawnMask[squareIndex], fileIndex, rankIndex)
+			}
+
+			// Capture masks are needed for every rank as they are also used to find squares attacked by pawns
+			_ = setIfOnBoard(&pieceMoveMasks.WhitePawnCaptureMask[squareIndex], fileIndex-1, rankIndex+1)
+			_ = setIfOnBoard(&pieceMoveMasks.WhitePawnCaptureMask[squareIndex], fileIndex+1, rankIndex+1)
+
+			// Black
+			if rankIndex > 0 {
+				_ = setIfOnBoard(&pieceMoveMasks.BlackPawnSlideMask[squareIndex], fileIndex, rankIndex-1)
+			}
+
+			if rankIndex == 6 {
+				_ = setIfOnBoard(&pieceMoveMasks.BlackPawnDoubleSlideMask[squareIndex], fileIndex, rankIndex-2)
+				_ = setIfOnBoard(&pieceMoveMasks.DoubleSlideEligiblePawnMask[squareIndex], fileIndex, rankIndex)
+			}
+
+			_ = setIfOnBoard(&pieceMoveMasks.BlackPawnCaptureMask[squareIndex], fileIndex-1, rankIndex-1)
+			_ = setIfOnBoard(&pieceMoveMasks.BlackPawnCaptureMask[squareIndex], fileIndex+1, rankIndex-1)
+		}
+	}
+}
+
+// rayAttacks returns the squares attacked along a ray from a square, up to and including the first blocker
+func rayAttacks(direction int, squareIndex int, occupied uint64) uint64 {
+	attacks := pieceMoveMasks.RayMask[direction][squareIndex]
+
+	var blocker int
+	if isPositiveDirection(direction) {
+		if bitScanForward(&blocker, attacks&occupied) {
+			attacks ^= pieceMoveMasks.RayMask[direction][blocker]
+		}
+	} else {
+		if bitScanReverse(&blocker, attacks&occupied) {
+			attacks ^= pieceMoveMasks.RayMask[direction][blocker]
+		}
+	}
+
+	return attacks
+}
+
+// BishopAttacks returns the squares attacked diagonally from a square, given the occupied squares
+func BishopAttacks(squareIndex int, occupied uint64) uint64 {
+	return rayAttacks(NorthEast, squareIndex, occupied) | rayAttacks(NorthWest, squareIndex, occupied) |
+		rayAttacks(SouthEast, squareIndex, occupied) | rayAttacks(SouthWest, squareIndex, occupied)
+}
+
+// RookAttacks returns the squares attacked along ranks and files from a square, given the occupied squares
+func RookAttacks(squareIndex int, occupied uint64) uint64 {
+	return rayAttacks(North, squareIndex, occupied) | rayAttacks(East, squareIndex, occupied) |
+		rayAttacks(South, squareIndex, occupied) | rayAttacks(West, squareIndex, occupied)
+}
+
+// KnightAttacks returns the squares a knight attacks from a square
+func KnightAttacks(squareIndex int) uint64 {
+	return pieceMoveMasks.KnightMoveMask[squareIndex]
+}
+
+// KingAttacks returns the squares a king attacks from a square
+func KingAttacks(squareIndex int) uint64 {
+	return pieceMoveMasks.KingMoveMask[squareIndex]
+}
+
+// PawnAttacks returns the squares a pawn of the given color attacks from a square
+func PawnAttacks(squareIndex int, white bool) uint64 {
+	if white {
+		return pieceMoveMasks.WhitePawnCaptureMask[squareIndex]
+	}
+	return pieceMoveMasks.BlackPawnCaptureMask[squareIndex]
+}
+
+func setIfOnBoard(bitboard *uint64, destinationFile int, destinationRank int) bool {
+	if destinationFile >= 0 && destinationFile < 8 && destinationRank >= 0 && destinationRank < 8 {
+		*bitboard |= 1 << (destinationRank*8 + destinationFile)
+		return true
+	}
+
+	return false
+}
+
+// printPieceMoveMasks writes a move mask to the debug log as an 8x8 grid
+func printPieceMoveMasks(moveMask uint64) {
+	var text strings.Builder
+
+	for rank := 7; rank >= 0; rank-- {
+		for file := 0; file < 8; file++ {
+			if moveMask&(1<<(rank*8+file)) != 0 {
+				fmt.Fprintf(&text, "X ")
+			} else {
+				fmt.Fprintf(&text, "- ")
+			}
+		}
+		fmt.Fprintln(&text)
+	}
+
+	logger.Debug("Move mask:\n%s", text.String())
+}
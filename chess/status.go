@@ -0,0 +1,65 @@
+package chess
+
+import "math/bits"
+
+// Status is whether the game is over in a position, and why
+type Status int
+
+const (
+	InProgress Status = iota
+	Checkmate
+	Stalemate
+
+	// Drawn as 50 moves by each side have been played without a capture or a pawn move
+	FiftyMoveRule
+
+	// Drawn as neither side has the material to give checkmate
+	InsufficientMaterial
+)
+
+var statusNames = [...]string{"In progress", "Checkmate", "Stalemate", "Fifty move rule", "Insufficient material"}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+// The squares of each color, for telling whether bishops are on the same color
+const (
+	lightSquares = 0x55aa55aa55aa55aa
+	darkSquares  = ^uint64(lightSquares)
+)
+
+// Status reports whether the game is over in the position: by checkmate or stalemate, or drawn by the
+// fifty move rule or insufficient material. A checkmate delivered on the fiftieth move still stands.
+// Draws by repetition are left to the caller, which knows the game's history.
+func (b *Board) Status() Status {
+	moveList, _ := b.GetMoves(make([]Move, 0, 256))
+
+	switch {
+	case len(moveList) == 0 && b.IsInCheck():
+		return Checkmate
+	case len(moveList) == 0:
+		return Stalemate
+	case b.getHalfMoveClock() >= 100:
+		return FiftyMoveRule
+	case b.hasInsufficientMaterial():
+		return InsufficientMaterial
+	}
+
+	return InProgress
+}
+
+// hasInsufficientMaterial reports whether no sequence of moves could lead to checkmate: when there are
+// only the kings and a single knight, or only the kings and bishops all on squares of the same color
+func (b *Board) hasInsufficientMaterial() bool {
+	if b.pawns|b.rooks|b.queens != 0 {
+		return false
+	}
+
+	minors := bits.OnesCount64(b.knights | b.bishops)
+	if minors <= 1 {
+		return true
+	}
+
+	return b.knights == 0 && (b.bishops&lightSquares == 0 || b.bishops&darkSquares == 0)
+}
@@ -0,0 +1,55 @@
+package chess
+
+// Piece types, used to index tables that hold a value per piece
+const (
+	Pawn = iota
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+	PieceTypeCount
+	NoPiece = PieceTypeCount
+)
+
+// Random keys for hashing positions (Zobrist hashing). A position's hash is the exclusive-or of the
+// keys for each piece on its square, the castling rights, any en passant file, and black to move.
+var zobristPieceKeys [2][PieceTypeCount][64]uint64
+var zobristCastlingKeys [16]uint64
+var zobristEnPassantKeys [8]uint64
+var zobristBlackToMoveKey uint64
+
+func init() {
+	// A fixed seed means hashes are the same from run to run, which helps with debugging
+	random := zobristRandom{state: 0x9E3779B97F4A7C15}
+
+	for color := 0; color < 2; color++ {
+		for piece := 0; piece < PieceTypeCount; piece++ {
+			for square := 0; square < 64; square++ {
+				zobristPieceKeys[color][piece][square] = random.next()
+			}
+		}
+	}
+
+	for i := range zobristCastlingKeys {
+		zobristCastlingKeys[i] = random.next()
+	}
+
+	for i := range zobristEnPassantKeys {
+		zobristEnPassantKeys[i] = random.next()
+	}
+
+	zobristBlackToMoveKey = random.next()
+}
+
+// zobristRandom is a small xorshift generator for the hash keys
+type zobristRandom struct {
+	state uint64
+}
+
+func (r *zobristRandom) next() uint64 {
+	r.state ^= r.state >> 12
+	r.state ^= r.state << 25
+	r.state ^= r.state >> 27
+	return r.state * 2685821657736338717
+}
@@ -0,0 +1,516 @@
+package engine
+
+import (
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/logger"
+	"goche/utility"
+)
+
+// Scores are in centipawns from the point of view of the side to move. Being mated scores -mateScore
+// plus the number of plies to the mate, so that the search prefers the quickest mate and the slowest
+// defeat.
+const (
+	infinity      = 32000
+	mateScore     = 31000
+	maxPly        = 128
+	mateThreshold = mateScore - maxPly
+)
+
+// Aspiration windows start this far either side of the previous iteration's score, from this depth
+const (
+	aspirationWindow = 25
+	aspirationDepth  = 4
+)
+
+// How often, in nodes, the search looks at the clock and for a request to stop
+const checkInterval = 2048
+
+// think searches the position by iterative deepening, keeping the principal variation found at each
+// completed depth, which the main thread reports
+func (s *searcher) think() {
+	moveList, err := s.board.GetMoves(make([]chess.Move, 0, 256))
+	if err != nil {
+		logger.Error("Move generation failed: %s", err)
+		return
+	}
+
+	for _, move := range moveList {
+		if s.isSearchMove(move.ToUciString()) {
+			s.rootMoves = append(s.rootMoves, move)
+		}
+	}
+
+	if len(s.rootMoves) == 0 {
+		return
+	}
+
+	for ply := range s.moveLists {
+		s.moveLists[ply] = make([]chess.Move, 0, 256)
+	}
+	s.hashes[0] = s.board.GetHash()
+
+	maxDepth := s.limits.Depth
+	if s.limits.Mate > 0 && (maxDepth == 0 || maxDepth > 2*s.limits.Mate) {
+		maxDepth = 2 * s.limits.Mate
+	}
+	if maxDepth <= 0 || maxDepth > maxPly {
+		maxDepth = maxPly
+	}
+
+	// Should the first iteration be cut short, play the first move rather than none
+	s.lines = []rootLine{{pv: []chess.Move{s.rootMoves[0]}}}
+
+	// Each line leaves out the first moves of the lines before it
+	lines := make([]rootLine, min(s.multiPV, len(s.rootMoves)))
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		if s.skipsDepth(depth) {
+			continue
+		}
+
+		for s.pvIndex = 0; s.pvIndex < len(lines); s.pvIndex++ {
+			previousScore := 0
+			if s.pvIndex < len(s.lines) {
+				previousScore = s.lines[s.pvIndex].score
+			}
+
+			s.selDepth = 0
+			s.depth = depth
+			score := s.aspirationSearch(depth, previousScore)
+			if s.aborted {
+				break
+			}
+
+			line := &lines[s.pvIndex]
+			line.pv = append(line.pv[:0], s.pvTable[0][:s.pvLength[0]]...)
+			line.score = score
+			line.selDepth = s.selDepth
+
+			// Search the line's move first in the next iteration, and leave it out of the lines after it
+			s.promoteRootMove(s.pvIndex, line.pv[0])
+		}
+
+		// An iteration cut short leaves the lines of the one before it
+		if s.aborted {
+			break
+		}
+
+		s.sortLines(lines)
+		s.lines = s.lines[:0]
+		for _, line := range lines {
+			s.lines = append(s.lines, rootLine{pv: append([]chess.Move(nil), line.pv...), score: line.score, selDepth: line.selDepth})
+		}
+		s.completedDepth = depth
+
+		if s.thread == 0 {
+			s.time.Update(s.lines[0].pv[0], searchScore(s.lines[0].score))
+			for i := range s.lines {
+				s.reporter.Thinking(s.progress(s, i))
+			}
+		}
+
+		if s.finishedEarly(depth) {
+			break
+		}
+	}
+}
+
+// progress describes a line found by a thread's last completed iteration, with the nodes searched by
+// all the threads. The line is numbered when there is more than one.
+func (s *searcher) progress(thread *searcher, index int) Progress {
+	line := thread.lines[index]
+
+	progress := Progress{
+		Depth:    thread.completedDepth,
+		SelDepth: line.selDepth,
+		Score:    searchScore(line.score),
+		Nodes:    s.totalNodes(),
+		Elapsed:  time.Since(s.startTime),
+		PV:       moveStrings(line.pv),
+		HashFull: s.tt.hashFull(),
+
+		Cutoffs:          s.cutoffs,
+		FirstMoveCutoffs: s.firstMoveCutoffs,
+	}
+
+	if s.multiPV > 1 {
+		progress.MultiPV = index + 1
+	}
+
+	return progress
+}
+
+// finishedEarly reports whether there is no point in searching deeper than the depth just completed
+func (s *searcher) finishedEarly(depth int) bool {
+	// A mate found within the depth searched cannot be improved on, but other lines may still be
+	resolved := true
+	for _, line := range s.lines {
+		if abs(line.score) < mateThreshold || depth < mateScore-abs(line.score) {
+			resolved = false
+		}
+	}
+	if resolved {
+		return true
+	}
+
+	if s.limits.Mate > 0 && s.lines[0].score >= mateScore-(2*s.limits.Mate-1) {
+		return true
+	}
+
+	// Only the main thread keeps time, and stops its helpers when it finishes
+	if s.thread == 0 && s.time.Timed() {
+		// With only one move to play, the time is better saved for later
+		if len(s.rootMoves) == 1 {
+			return true
+		}
+
+		if elapsed, running := s.clockElapsed(); running && !s.time.StartIteration(elapsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// aspirationSearch searches to the depth with a narrow window around the previous iteration's score,
+// widening it and searching again whenever the score falls outside it
+func (s *searcher) aspirationSearch(depth int, previousScore int) int {
+	alpha, beta := -infinity, infinity
+	delta := aspirationWindow
+
+	if depth >= aspirationDepth && abs(previousScore) < mateThreshold {
+		alpha = previousScore - delta
+		beta = previousScore + delta
+	}
+
+	for {
+		score := s.negamax(depth, 0, alpha, beta)
+		if s.aborted {
+			return score
+		}
+
+		switch {
+		case score <= alpha && alpha > -infinity:
+			alpha = max(score-delta, -infinity)
+		case score >= beta && beta < infinity:
+			beta = min(score+delta, infinity)
+		default:
+			return score
+		}
+
+		delta *= 2
+	}
+}
+
+// negamax is a fail-soft principal variation search of the position to the given depth, returning its
+// score within alpha and beta, or a bound on it outside them
+func (s *searcher) negamax(depth int, ply int, alpha int, beta int) int {
+	if depth <= 0 {
+		return s.quiesce(ply, 0, alpha, beta)
+	}
+
+	s.pvLength[ply] = ply
+
+	s.checkLimits()
+	if s.aborted {
+		return 0
+	}
+	s.nodes.Add(1)
+	s.selDepth = max(s.selDepth, ply)
+
+	board := &s.board
+
+	if ply > 0 {
+		if board.HalfMoveClock() >= 100 || s.isRepetition(ply) {
+			return 0
+		}
+
+		// No line from here can beat a mate already found nearer the root
+		alpha = max(alpha, -mateScore+ply)
+		beta = min(beta, mateScore-ply-1)
+		if alpha >= beta {
+			return alpha
+		}
+	}
+
+	// Look further when in check, so that mates and escapes are not lost over the horizon
+	inCheck := board.IsInCheck()
+	if inCheck {
+		depth++
+	}
+
+	if ply >= maxPly {
+		return Evaluate(board)
+	}
+
+	// A result from the table searched at least as deep settles a null-window search outright.
+	// Principal variation nodes are always searched, so that the line reported is complete.
+	hash := s.hashes[ply]
+	var hashMove chess.Move
+	if entry, found := s.tt.probe(hash, ply); found {
+		hashMove = entry.move
+
+		if ply > 0 && beta-alpha == 1 && entry.depth >= depth {
+			switch {
+			case entry.bound == boundExact,
+				entry.bound == boundLower && entry.score >= beta,
+				entry.bound == boundUpper && entry.score <= alpha:
+				return entry.score
+			}
+		}
+	}
+
+	pvNode := beta-alpha > 1
+	p := &s.parameters
+
+	// Without a hash move to try first the node is probably not worth its full depth
+	if p.internalIterativeReductions && hashMove == 0 && depth >= p.iirMinDepth {
+		depth--
+	}
+
+	staticEval := -infinity
+	if !inCheck {
+		staticEval = Evaluate(board)
+	}
+
+	if ply > 0 && !pvNode && !inCheck {
+		if score, pruned := s.pruneNode(depth, ply, alpha, beta, staticEval); pruned {
+			return score
+		}
+	}
+
+	var moveList []chess.Move
+	if ply == 0 {
+		moveList = s.rootMoves[s.pvIndex:]
+	} else {
+		moveList, _ = board.GetMoves(s.moveLists[ply][:0])
+		s.moveLists[ply] = moveList
+		s.scoreMoves(moveList, ply, hashMove)
+	}
+
+	if len(moveList) == 0 {
+		if inCheck {
+			return -mateScore + ply
+		}
+		return 0
+	}
+
+	bestScore := -infinity
+	var bestMove chess.Move
+	originalAlpha := alpha
+	quietsTried := s.quietsTried[ply][:0]
+	quietCount := 0
+
+	// Quiet moves may be pruned at shallow depths when the position is hopeless or they come late
+	futile := p.futility && !pvNode && !inCheck && depth <= p.futilityDepth && staticEval+p.futilityMargin*depth <= alpha
+	lateMoves := p.lateMovePruning && !pvNode && !inCheck && depth <= p.lmpDepth
+
+	for i := range moveList {
+		// The root moves are kept in order of the previous iteration's results instead
+		if ply > 0 {
+			s.pickMove(moveList, ply, i)
+		}
+		move := moveList[i]
+
+		if ply == 0 {
+			s.currMove = move
+			s.currMoveNumber = s.pvIndex + i + 1
+			s.reportCurrMove()
+		}
+
+		quiet := isQuiet(board, move)
+		s.moveStack[ply] = move
+		s.movedPieces[ply], _ = board.PieceAt(int(move.From()))
+
+		undo := board.MakeMove(move)
+		s.hashes[ply+1] = board.GetHash()
+		givesCheck := board.IsInCheck()
+
+		if quiet && !givesCheck && i > 0 && bestScore > -mateThreshold {
+			if futile || lateMoves && quietCount >= p.lateMoveCount(depth) {
+				board.UnmakeMove(undo)
+				continue
+			}
+		}
+
+		// The first move is expected to be best, and the rest are searched with a null window to prove
+		// that they are worse, searching again only if one turns out not to be. Late quiet moves are
+		// searched less deeply at first, and again at full depth should they turn out better.
+		var score int
+		if i == 0 {
+			score = -s.negamax(depth-1, ply+1, -beta, -alpha)
+		} else {
+			reduction := 0
+			if p.lateMoveReductions && quiet && !inCheck && !givesCheck && depth >= p.lmrMinDepth {
+				reduction = s.lateMoveReduction(depth, ply, i, pvNode)
+			}
+
+			score = -s.negamax(depth-1-reduction, ply+1, -alpha-1, -alpha)
+			if score > alpha && reduction > 0 {
+				score = -s.negamax(depth-1, ply+1, -alpha-1, -alpha)
+			}
+			if score > alpha && score < beta {
+				score = -s.negamax(depth-1, ply+1, -beta, -alpha)
+			}
+		}
+
+		board.UnmakeMove(undo)
+
+		if s.aborted {
+			return 0
+		}
+
+		if score > bestScore {
+			bestScore = score
+		}
+
+		if score > alpha {
+			alpha = score
+			bestMove = move
+
+			s.pvTable[ply][ply] = move
+			copy(s.pvTable[ply][ply+1:], s.pvTable[ply+1][ply+1:s.pvLength[ply+1]])
+			s.pvLength[ply] = s.pvLength[ply+1]
+
+			if alpha >= beta {
+				s.cutoffs++
+				if i == 0 {
+					s.firstMoveCutoffs++
+				}
+
+				if quiet {
+					s.updateQuietHistory(ply, depth, move, quietsTried)
+				}
+				break
+			}
+		}
+
+		if quiet {
+			quietCount++
+			if len(quietsTried) < maxQuietsTried {
+				quietsTried = append(quietsTried, move)
+			}
+		}
+	}
+
+	// With no move raising alpha, bestMove stays empty and the table keeps any move it already had. A
+	// root search leaving out the best lines' moves does not score the position.
+	if ply > 0 || s.pvIndex == 0 {
+		bound := boundUpper
+		switch {
+		case bestScore >= beta:
+			bound = boundLower
+		case bestScore > originalAlpha:
+			bound = boundExact
+		}
+		s.tt.store(hash, ply, bestMove, bestScore, depth, bound)
+	}
+
+	return bestScore
+}
+
+// isRepetition reports whether the position at the ply has already occurred in the line searched.
+// Positions before the last capture or pawn move cannot repeat, so the search looks back no further.
+func (s *searcher) isRepetition(ply int) bool {
+	earliest := max(0, ply-s.board.HalfMoveClock())
+
+	for previous := ply - 2; previous >= earliest; previous -= 2 {
+		if s.hashes[previous] == s.hashes[ply] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkLimits abandons the search when it is asked to stop, or once it has searched to at least one
+// ply and reaches its node or time limit. The limits are kept by the main thread, counting the nodes of
+// all the threads.
+func (s *searcher) checkLimits() {
+	if s.thread == 0 && s.completedDepth > 0 && s.limits.Nodes > 0 && s.totalNodes() >= uint64(s.limits.Nodes) {
+		s.aborted = true
+		return
+	}
+
+	if s.nodes.Load()%checkInterval != 0 {
+		return
+	}
+
+	if s.stopped() {
+		s.aborted = true
+		return
+	}
+
+	s.reportCurrMove()
+
+	if s.thread == 0 && s.completedDepth > 0 {
+		if elapsed, running := s.clockElapsed(); running && s.time.Expired(elapsed) {
+			s.aborted = true
+		}
+	}
+}
+
+// reportCurrMove reports the root move the main thread is searching, with the nodes searched so far,
+// if the reporter follows it and it has not been reported too recently
+func (s *searcher) reportCurrMove() {
+	if s.thread != 0 || s.currMove == 0 {
+		return
+	}
+
+	reporter, ok := s.reporter.(CurrMoveReporter)
+	if !ok || !s.throttle.Allow() {
+		return
+	}
+
+	reporter.SearchingMove(Progress{
+		Depth:          s.depth,
+		Nodes:          s.totalNodes(),
+		Elapsed:        time.Since(s.startTime),
+		HashFull:       s.tt.hashFull(),
+		CurrMove:       s.currMove.ToUciString(),
+		CurrMoveNumber: s.currMoveNumber,
+	})
+}
+
+// clockElapsed returns the time used on our clock, and whether it is running, which it is not while
+// pondering
+func (s *searcher) clockElapsed() (time.Duration, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.pondering {
+		return 0, false
+	}
+	return time.Since(s.clockStart), true
+}
+
+// searchScore converts a search score for reporting, with mates given in moves
+func searchScore(score int) utility.Score {
+	switch {
+	case score >= mateThreshold:
+		return utility.ScoreMate((mateScore - score + 1) / 2)
+	case score <= -mateThreshold:
+		return utility.ScoreMate(-(mateScore + score) / 2)
+	}
+
+	return utility.ScoreCentipawns(score)
+}
+
+// moveStrings returns moves in UCI notation
+func moveStrings(moves []chess.Move) []string {
+	text := make([]string, len(moves))
+	for i, move := range moves {
+		text[i] = move.ToUciString()
+	}
+	return text
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
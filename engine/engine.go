@@ -0,0 +1,197 @@
+// Package engine is the chess engine itself: the position to search, the options that tune it, and
+// the search, driven through Go method calls. The protocol front ends are adapters over it, and a Go
+// program can embed it directly, running as many engines side by side as it likes:
+//
+//	e := engine.New()
+//	board, _ := chess.NewBoard(chess.FenStartingPosition)
+//	e.SetPosition(board)
+//
+//	reporter := engine.NewChannelReporter(16)
+//	e.Go(engine.Limits{MoveTime: time.Second}, reporter)
+//
+//	result := <-reporter.Result
+//	fmt.Println(result.BestMove)
+package engine
+
+import (
+	"sync"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/option"
+)
+
+// Engine searches positions on behalf of a single caller. Its methods may be called from any goroutine.
+type Engine struct {
+	// Held by Go from stopping the previous search until the next has started, so that searches
+	// started at the same time from different goroutines follow one another
+	startMutex sync.Mutex
+
+	mutex    sync.Mutex
+	position *chess.Board
+	options  *option.Registry
+	search   *searcher
+	tt       *transpositionTable
+
+	// The move history of each search thread, kept from one search to the next
+	histories []*moveHistory
+
+	// Option values
+	ponder       bool
+	moveOverhead time.Duration
+	multiPV      int
+	parameters   searchParameters
+}
+
+// New creates an engine set up with the starting position and default options
+func New() *Engine {
+	e := &Engine{
+		moveOverhead: defaultMoveOverhead * time.Millisecond,
+		multiPV:      1,
+		parameters:   defaultSearchParameters(),
+	}
+
+	e.options = newOptions(e)
+	e.tt = newTranspositionTable(defaultHashSize)
+	e.histories = []*moveHistory{{}}
+	e.position, _ = chess.NewBoard(chess.FenStartingPosition)
+
+	return e
+}
+
+// Options returns the engine's options, for declaring them to a GUI
+func (e *Engine) Options() *option.Registry {
+	return e.options
+}
+
+// SetOption sets an option by name, as in 'setoption name Ponder value true'
+func (e *Engine) SetOption(name string, value string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.options.Set(name, value)
+}
+
+// NewGame tells the engine that the next search is from a different game, clearing what it
+// remembers of the previous one
+func (e *Engine) NewGame() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.tt.clear()
+
+	// A search still running keeps the histories it has, rather than having them cleared under it
+	for i := range e.histories {
+		e.histories[i] = &moveHistory{}
+	}
+}
+
+// SetPosition sets the position for the next search. The engine keeps its own copy of the board.
+func (e *Engine) SetPosition(board *chess.Board) {
+	position := *board
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.position = &position
+}
+
+// Position returns a copy of the position the engine will search
+func (e *Engine) Position() *chess.Board {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	position := *e.position
+	return &position
+}
+
+// Go searches the current position in the background within the limits, sending progress and the
+// best move to the reporter. A search already running is stopped first, and reports its own best move.
+// If this build has failed its integrity check, its policy may limit the search, or refuse it, in
+// which case the null move is reported. A reporter must not start a search itself, as Go waits for
+// the search it replaces to report.
+func (e *Engine) Go(limits Limits, reporter Reporter) {
+	refused := applyProtectionPolicy(&limits)
+
+	e.startMutex.Lock()
+	defer e.startMutex.Unlock()
+
+	e.Stop(false)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.tt.newSearch()
+	for _, history := range e.histories {
+		history.age()
+	}
+	timeManager := NewTimeManager(limits, e.position, e.moveOverhead, e.ponder)
+	e.search = newSearcher(limits, e.position, e.parameters, e.multiPV, timeManager, e.tt, e.histories, reporter)
+	e.search.refused = refused
+	e.search.start()
+}
+
+// Stop asks the search in progress, if any, to finish and waits for it to do so. A quiet stop does not
+// report a best move.
+func (e *Engine) Stop(quiet bool) {
+	// The search is stopped without the lock, as its reporter may call back into the engine
+	search := e.currentSearch()
+	if search == nil {
+		return
+	}
+
+	search.stop(quiet)
+}
+
+// PonderHit tells a pondering search that the ponder move was played, so that it continues with its
+// normal time control. It returns false if no search is in progress.
+func (e *Engine) PonderHit() bool {
+	search := e.currentSearch()
+	if search == nil || search.finished() {
+		return false
+	}
+
+	search.ponderhit()
+	return true
+}
+
+// Searching reports whether a search is in progress
+func (e *Engine) Searching() bool {
+	search := e.currentSearch()
+	return search != nil && !search.finished()
+}
+
+// Wait waits for the search in progress, if any, to finish. A search that runs until it is stopped
+// must be stopped from another goroutine.
+func (e *Engine) Wait() {
+	search := e.currentSearch()
+	if search == nil {
+		return
+	}
+
+	<-search.done
+}
+
+// Finish lets a search with a natural end complete, and stops one that would otherwise wait to be
+// stopped, then waits for its best move to be reported
+func (e *Engine) Finish() {
+	search := e.currentSearch()
+	if search == nil {
+		return
+	}
+
+	if search.runsIndefinitely() {
+		search.stop(false)
+	} else {
+		<-search.done
+	}
+}
+
+// currentSearch returns the latest search, which may have finished, or nil if none has been started
+func (e *Engine) currentSearch() *searcher {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.search
+}
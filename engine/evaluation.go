@@ -0,0 +1,364 @@
+package engine
+
+import (
+	"math/bits"
+
+	// Internal references
+	"goche/chess"
+	"goche/utility"
+)
+
+// Evaluation terms, which are reported separately by the 'eval' command
+const (
+	termMaterial = iota
+	termPosition
+	termMobility
+	termPawnStructure
+	termKingSafety
+	termCount
+)
+
+var termNames = [termCount]string{
+	"Material",
+	"Position",
+	"Mobility",
+	"Pawn structure",
+	"King safety",
+}
+
+// Material values in centipawns, by piece type
+var pieceValues = [chess.PieceTypeCount]int{100, 320, 330, 500, 900, 0}
+
+const bishopPairBonus = 30
+
+// Game phase weights, by piece type. The phase runs from the total (all pieces on the board) down to
+// zero (kings and pawns only) and is used to blend middlegame and endgame scores
+var phaseWeights = [chess.PieceTypeCount]int{0, 1, 1, 2, 4, 0}
+
+const totalPhase = 24
+
+// Mobility is scored relative to a typical number of squares for each piece type
+var mobilityWeights = [chess.PieceTypeCount]int{0, 4, 5, 2, 1, 0}
+var mobilityBaselines = [chess.PieceTypeCount]int{0, 4, 7, 7, 14, 0}
+
+// Pawn structure
+const (
+	doubledPawnPenalty  = 15
+	isolatedPawnPenalty = 15
+)
+
+// Bonus for a passed pawn, by rank from the pawn's own side
+var passedPawnBonus = [8]int{0, 5, 10, 20, 35, 60, 100, 0}
+
+// Bonus for each pawn sheltering the king, in the middlegame
+const pawnShieldBonus = 10
+
+// Piece-square tables, written from white's point of view with the 8th rank first, as they would
+// appear on a diagram
+var pieceSquareTables = [chess.PieceTypeCount][64]int{
+	// Pawn
+	{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	// Knight
+	{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	},
+	// Bishop
+	{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	},
+	// Rook
+	{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	},
+	// Queen
+	{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	},
+	// King, in the middlegame
+	{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	},
+}
+
+// The king wants to be active in the endgame
+var kingEndgameTable = [64]int{
+	-50, -40, -30, -20, -20, -30, -40, -50,
+	-30, -20, -10, 0, 0, -10, -20, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -30, 0, 0, 0, 0, -30, -30,
+	-50, -30, -30, -30, -30, -30, -30, -50,
+}
+
+// fileMasks holds a mask of each file
+var fileMasks = func() [8]uint64 {
+	var masks [8]uint64
+	for file := 0; file < 8; file++ {
+		masks[file] = 0x0101010101010101 << file
+	}
+	return masks
+}()
+
+// evaluation holds the score for each term, for white and for black, in centipawns
+type evaluation struct {
+	scores [termCount][2]int
+	phase  int
+}
+
+// Evaluate returns the static evaluation of the position in centipawns, from the point of view of the side to move
+func Evaluate(b *chess.Board) int {
+	e := evaluateTerms(b)
+	if b.IsWhiteToMove() {
+		return e.total()
+	}
+	return -e.total()
+}
+
+// Term is one part of the static evaluation, scored for each side in centipawns
+type Term struct {
+	Name  string
+	White int
+	Black int
+}
+
+// Breakdown is the static evaluation of a position term by term, as shown by the 'eval' command
+type Breakdown struct {
+	Terms []Term
+
+	// The game phase, from MaxPhase with all the pieces on the board down to 0 in the endgame
+	Phase    int
+	MaxPhase int
+
+	// The evaluation in centipawns from white's point of view
+	Total int
+}
+
+// EvaluateTerms returns the static evaluation of the position, term by term
+func EvaluateTerms(b *chess.Board) Breakdown {
+	e := evaluateTerms(b)
+
+	breakdown := Breakdown{Phase: e.phase, MaxPhase: totalPhase, Total: e.total()}
+	for term := 0; term < termCount; term++ {
+		breakdown.Terms = append(breakdown.Terms, Term{Name: termNames[term], White: e.scores[term][0], Black: e.scores[term][1]})
+	}
+
+	return breakdown
+}
+
+// total returns the evaluation in centipawns from white's point of view
+func (e *evaluation) total() int {
+	total := 0
+	for term := 0; term < termCount; term++ {
+		total += e.scores[term][0] - e.scores[term][1]
+	}
+	return total
+}
+
+// gamePhase returns how far the game is from the endgame, from totalPhase with all the pieces on the
+// board down to zero with only kings and pawns
+func gamePhase(b *chess.Board) int {
+	phase := 0
+	for pieceType := chess.Pawn; pieceType < chess.PieceTypeCount; pieceType++ {
+		phase += phaseWeights[pieceType] * bits.OnesCount64(b.Pieces(pieceType, true)|b.Pieces(pieceType, false))
+	}
+
+	return min(phase, totalPhase)
+}
+
+// evaluateTerms evaluates the position term by term for each side
+func evaluateTerms(b *chess.Board) *evaluation {
+	e := &evaluation{phase: gamePhase(b)}
+
+	occupied := b.Occupied()
+
+	for colorIndex, white := range []bool{true, false} {
+		ownPieces := b.ColorPieces(white)
+
+		if bits.OnesCount64(b.Pieces(chess.Bishop, white)) >= 2 {
+			e.scores[termMaterial][colorIndex] += bishopPairBonus
+		}
+
+		for pieceType := chess.Pawn; pieceType < chess.PieceTypeCount; pieceType++ {
+			pieceSet := b.Pieces(pieceType, white)
+
+			for pieceSet != 0 {
+				pieceIndex := bits.TrailingZeros64(pieceSet)
+				pieceSet ^= 1 << pieceIndex
+
+				// Tables are written with the 8th rank first, so white squares flip and black squares do not
+				tableIndex := utility.If(white, pieceIndex^56, pieceIndex)
+
+				e.scores[termMaterial][colorIndex] += pieceValues[pieceType]
+
+				if pieceType == chess.King {
+					middlegame := pieceSquareTables[chess.King][tableIndex]
+					endgame := kingEndgameTable[tableIndex]
+					e.scores[termPosition][colorIndex] += (middlegame*e.phase + endgame*(totalPhase-e.phase)) / totalPhase
+				} else {
+					e.scores[termPosition][colorIndex] += pieceSquareTables[pieceType][tableIndex]
+				}
+
+				var attacks uint64
+				switch pieceType {
+				case chess.Knight:
+					attacks = chess.KnightAttacks(pieceIndex)
+				case chess.Bishop:
+					attacks = chess.BishopAttacks(pieceIndex, occupied)
+				case chess.Rook:
+					attacks = chess.RookAttacks(pieceIndex, occupied)
+				case chess.Queen:
+					attacks = chess.BishopAttacks(pieceIndex, occupied) | chess.RookAttacks(pieceIndex, occupied)
+				default:
+					continue
+				}
+
+				mobility := bits.OnesCount64(attacks &^ ownPieces)
+				e.scores[termMobility][colorIndex] += mobilityWeights[pieceType] * (mobility - mobilityBaselines[pieceType])
+			}
+		}
+
+		ownPawns := b.Pieces(chess.Pawn, white)
+		e.scores[termPawnStructure][colorIndex] = pawnStructure(ownPawns, b.Pieces(chess.Pawn, !white), white)
+		e.scores[termKingSafety][colorIndex] = kingSafety(b, ownPawns, white) * e.phase / totalPhase
+	}
+
+	return e
+}
+
+// pawnStructure scores doubled, isolated and passed pawns for one side
+func pawnStructure(ownPawns uint64, opponentPawns uint64, white bool) int {
+	score := 0
+
+	for file := 0; file < 8; file++ {
+		count := bits.OnesCount64(ownPawns & fileMasks[file])
+		if count == 0 {
+			continue
+		}
+
+		if count > 1 {
+			score -= doubledPawnPenalty * (count - 1)
+		}
+
+		neighbours := uint64(0)
+		if file > 0 {
+			neighbours |= fileMasks[file-1]
+		}
+		if file < 7 {
+			neighbours |= fileMasks[file+1]
+		}
+
+		if ownPawns&neighbours == 0 {
+			score -= isolatedPawnPenalty * count
+		}
+	}
+
+	pawnSet := ownPawns
+
+	for pawnSet != 0 {
+		pawnIndex := bits.TrailingZeros64(pawnSet)
+		pawnSet ^= 1 << pawnIndex
+
+		if opponentPawns&passedPawnMask(pawnIndex, white) == 0 {
+			rank := utility.If(white, pawnIndex/8, 7-pawnIndex/8)
+			score += passedPawnBonus[rank]
+		}
+	}
+
+	return score
+}
+
+// passedPawnMask returns the squares ahead of a pawn, on its own and neighbouring files, that must be
+// free of opposing pawns for it to be passed
+func passedPawnMask(pawnIndex int, white bool) uint64 {
+	file := pawnIndex % 8
+	rank := pawnIndex / 8
+
+	files := fileMasks[file]
+	if file > 0 {
+		files |= fileMasks[file-1]
+	}
+	if file < 7 {
+		files |= fileMasks[file+1]
+	}
+
+	if white {
+		if rank == 7 {
+			return 0
+		}
+		return files & (^uint64(0) << ((rank + 1) * 8))
+	}
+
+	if rank == 0 {
+		return 0
+	}
+	return files & (^uint64(0) >> ((8 - rank) * 8))
+}
+
+// kingSafety scores the pawns sheltering a side's king on the two ranks in front of it
+func kingSafety(b *chess.Board, ownPawns uint64, white bool) int {
+	kingIndex := b.KingIndex(white)
+	file := kingIndex % 8
+	rank := kingIndex / 8
+
+	shield := uint64(0)
+	for step := 1; step <= 2; step++ {
+		shieldRank := utility.If(white, rank+step, rank-step)
+		if shieldRank < 0 || shieldRank > 7 {
+			break
+		}
+
+		for shieldFile := file - 1; shieldFile <= file+1; shieldFile++ {
+			if shieldFile >= 0 && shieldFile < 8 {
+				shield |= uint64(1) << (shieldRank*8 + shieldFile)
+			}
+		}
+	}
+
+	return pawnShieldBonus * bits.OnesCount64(ownPawns&shield)
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	// Internal references
+	"goche/chess"
+)
+
+// Material values used to play out exchanges. The king is worth more than everything else together,
+// so that a capture leaving it to be taken is never chosen.
+var exchangeValues = [chess.PieceTypeCount]int{100, 320, 330, 500, 900, 20000}
+
+// staticExchange returns the material the side to move gains by a capture, once both sides have
+// recaptured on its square for as long as it pays them to, least valuable attacker first. Pieces
+// behind the attackers on the same line join in as the ones in front are exchanged.
+func staticExchange(board *chess.Board, move chess.Move) int {
+	from := int(move.From())
+	to := int(move.To())
+
+	var gain [32]int
+	gain[0] = capturedValue(board, move)
+
+	attacker, white := board.PieceAt(from)
+	if move.IsPromotion() {
+		attacker = move.PromotionPieceType()
+	}
+
+	occupied := board.Occupied() &^ (1 << from)
+	if board.IsCapture(move) && board.Occupied()&(1<<to) == 0 {
+		// En passant takes a pawn that is not on the target square
+		occupied &^= 1 << (to + (from/8-to/8)*8)
+	}
+
+	sliders := board.Pieces(chess.Bishop, true) | board.Pieces(chess.Bishop, false) |
+		board.Pieces(chess.Rook, true) | board.Pieces(chess.Rook, false) |
+		board.Pieces(chess.Queen, true) | board.Pieces(chess.Queen, false)
+
+	attackers := board.AttackersTo(to, occupied) & occupied
+
+	depth := 0
+	for {
+		white = !white
+		fromSet, nextAttacker := leastValuableAttacker(board, attackers&board.ColorPieces(white))
+		if fromSet == 0 {
+			break
+		}
+
+		// Speculatively take the piece now on the square, which can be undone if it does not pay
+		depth++
+		gain[depth] = exchangeValues[attacker] - gain[depth-1]
+		if max(-gain[depth-1], gain[depth]) < 0 || depth == len(gain)-1 {
+			break
+		}
+
+		occupied &^= fromSet
+		attackers = (attackers | board.AttackersTo(to, occupied)&sliders) & occupied
+		attacker = nextAttacker
+	}
+
+	// Either side may stop capturing when it is ahead, so work back to the value of the first capture
+	for ; depth > 0; depth-- {
+		gain[depth-1] = -max(-gain[depth-1], gain[depth])
+	}
+
+	return gain[0]
+}
+
+// capturedValue returns the material a move wins immediately, including what a promotion adds
+func capturedValue(board *chess.Board, move chess.Move) int {
+	value := 0
+
+	if board.IsCapture(move) {
+		captured, _ := board.PieceAt(int(move.To()))
+		if captured == chess.NoPiece {
+			captured = chess.Pawn
+		}
+		value = exchangeValues[captured]
+	}
+
+	if move.IsPromotion() {
+		value += exchangeValues[move.PromotionPieceType()] - exchangeValues[chess.Pawn]
+	}
+
+	return value
+}
+
+// leastValuableAttacker returns the square, as a bitboard, and type of the least valuable of the
+// attackers, or an empty bitboard if there are none
+func leastValuableAttacker(board *chess.Board, attackers uint64) (uint64, int) {
+	if attackers == 0 {
+		return 0, chess.NoPiece
+	}
+
+	for pieceType := chess.Pawn; pieceType <= chess.King; pieceType++ {
+		pieces := attackers & (board.Pieces(pieceType, true) | board.Pieces(pieceType, false))
+		if pieces != 0 {
+			return pieces & -pieces, pieceType
+		}
+	}
+
+	return 0, chess.NoPiece
+}
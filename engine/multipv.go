@@ -0,0 +1,42 @@
+package engine
+
+import (
+	// Internal references
+	"goche/chess"
+)
+
+// The most lines the MultiPV option allows
+const maxMultiPV = 256
+
+// rootLine is a line found from the root by an iteration, with its score and selective depth
+type rootLine struct {
+	pv       []chess.Move
+	score    int
+	selDepth int
+}
+
+// promoteRootMove moves the root move to the index, ahead of the moves after it, so that it is searched
+// first in the next iteration and left out of the lines after it in this one
+func (s *searcher) promoteRootMove(index int, move chess.Move) {
+	for i := index; i < len(s.rootMoves); i++ {
+		if s.rootMoves[i] == move {
+			copy(s.rootMoves[index+1:i+1], s.rootMoves[index:i])
+			s.rootMoves[index] = move
+			return
+		}
+	}
+}
+
+// sortLines sorts the lines best first, and the root moves they start with to match. A line searched
+// later may score better than one before it, as it was searched with a different window.
+func (s *searcher) sortLines(lines []rootLine) {
+	for i := 1; i < len(lines); i++ {
+		for j := i; j > 0 && lines[j].score > lines[j-1].score; j-- {
+			lines[j], lines[j-1] = lines[j-1], lines[j]
+		}
+	}
+
+	for i, line := range lines {
+		s.rootMoves[i] = line.pv[0]
+	}
+}
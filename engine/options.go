@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"time"
+
+	// Internal references
+	"goche/option"
+)
+
+// newOptions creates the registry of options that the engine advertises, e.g. in response to 'uci'.
+// Each option's change handler applies the new value to the engine.
+func newOptions(e *Engine) *option.Registry {
+	options := option.NewRegistry()
+
+	// Tells the engine whether the GUI may ask it to ponder, which affects its use of time
+	options.AddCheck("Ponder", false, func(value bool) error {
+		e.ponder = value
+		return nil
+	})
+
+	// The time to allow for each move to reach the GUI, in milliseconds, which is kept back from the
+	// time the engine thinks for
+	options.AddSpin("Move Overhead", defaultMoveOverhead, 0, maxMoveOverhead, func(value int) error {
+		e.moveOverhead = time.Duration(value) * time.Millisecond
+		return nil
+	})
+
+	// The number of threads to search with, all sharing the transposition table
+	options.AddSpin("Threads", 1, 1, maxThreads, func(value int) error {
+		for len(e.histories) < value {
+			e.histories = append(e.histories, &moveHistory{})
+		}
+		e.histories = e.histories[:value]
+		return nil
+	})
+
+	// The size of the transposition table in megabytes. A new table replaces the old one, so what
+	// it held is lost.
+	options.AddSpin("Hash", defaultHashSize, minHashSize, maxHashSize, func(value int) error {
+		e.tt = newTranspositionTable(value)
+		return nil
+	})
+
+	options.AddButton("Clear Hash", func() error {
+		e.tt.clear()
+		return nil
+	})
+
+	// The number of best lines to search and report, each leaving out the first moves of those before it
+	options.AddSpin("MultiPV", 1, 1, maxMultiPV, func(value int) error {
+		e.multiPV = value
+		return nil
+	})
+
+	addSearchParameters(options, &e.parameters)
+
+	return options
+}
+
+// addSearchParameters adds the hidden options that switch and tune the selective search
+func addSearchParameters(options *option.Registry, p *searchParameters) {
+	addSwitch := func(name string, field *bool) {
+		options.AddCheck(name, *field, func(value bool) error {
+			*field = value
+			return nil
+		}).Hide()
+	}
+
+	addTunable := func(name string, field *int, min int, max int) {
+		options.AddSpin(name, *field, min, max, func(value int) error {
+			*field = value
+			return nil
+		}).Hide()
+	}
+
+	addSwitch("Null Move", &p.nullMove)
+	addTunable("Null Move Min Depth", &p.nullMoveMinDepth, 1, 16)
+	addTunable("Null Move Reduction", &p.nullMoveReduction, 1, 8)
+	addTunable("Null Move Depth Divisor", &p.nullMoveDepthDivisor, 1, 32)
+	addTunable("Null Move Verify Depth", &p.nullMoveVerifyDepth, 1, maxPly)
+
+	addSwitch("Late Move Reductions", &p.lateMoveReductions)
+	addTunable("LMR Min Depth", &p.lmrMinDepth, 1, 16)
+	addTunable("LMR Base", &p.lmrBase, 0, 300)
+	addTunable("LMR Divisor", &p.lmrDivisor, 50, 1000)
+
+	addSwitch("Reverse Futility", &p.reverseFutility)
+	addTunable("Reverse Futility Depth", &p.reverseFutilityDepth, 1, 16)
+	addTunable("Reverse Futility Margin", &p.reverseFutilityMargin, 0, 1000)
+
+	addSwitch("Futility", &p.futility)
+	addTunable("Futility Depth", &p.futilityDepth, 1, 16)
+	addTunable("Futility Margin", &p.futilityMargin, 0, 1000)
+
+	addSwitch("Razoring", &p.razoring)
+	addTunable("Razoring Depth", &p.razoringDepth, 1, 8)
+	addTunable("Razoring Margin", &p.razoringMargin, 0, 2000)
+
+	addSwitch("Late Move Pruning", &p.lateMovePruning)
+	addTunable("LMP Depth", &p.lmpDepth, 1, 16)
+	addTunable("LMP Base", &p.lmpBase, 0, 64)
+
+	addSwitch("Internal Iterative Reductions", &p.internalIterativeReductions)
+	addTunable("IIR Min Depth", &p.iirMinDepth, 1, 16)
+}
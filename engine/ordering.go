@@ -0,0 +1,199 @@
+package engine
+
+import (
+	// Internal references
+	"goche/chess"
+	"goche/utility"
+)
+
+// Moves are searched in order of these scores: the hash move, then captures that do not lose material,
+// the killer moves and the countermove, the other quiet moves by their history, and last the captures
+// that lose material and the promotions to anything but a queen
+const (
+	hashMoveScore     = 4_000_000
+	goodCaptureScore  = 2_000_000
+	firstKillerScore  = 1_000_003
+	secondKillerScore = 1_000_002
+	counterMoveScore  = 1_000_001
+	badCaptureScore   = -2_000_000
+)
+
+// History scores are kept within this bound by scaling each update down as they approach it
+const maxHistory = 16384
+
+// The number of quiet moves at a node whose history is lowered when a later one proves best
+const maxQuietsTried = 64
+
+// moveHistory is what searches learn about quiet moves that carries over from one search to the next:
+// the moves that refuted each move of the opponent, and how often each move has proved best. It is
+// owned by a single search at a time.
+type moveHistory struct {
+	// Indexed by the square moved from and to of the opponent's move
+	counterMoves [64][64]chess.Move
+
+	// Butterfly history, indexed by the side to move and the squares moved from and to
+	butterfly [2][64][64]int16
+
+	// Continuation history, indexed by the side to move, the piece type and square moved to of the
+	// opponent's move, and the piece type and square moved to of the reply
+	continuation [2][chess.PieceTypeCount][64][chess.PieceTypeCount][64]int16
+}
+
+// age halves the history scores, so that what the next search learns soon outweighs them
+func (h *moveHistory) age() {
+	for side := range h.butterfly {
+		for from := range h.butterfly[side] {
+			for to := range h.butterfly[side][from] {
+				h.butterfly[side][from][to] /= 2
+			}
+		}
+	}
+
+	for side := range h.continuation {
+		for previousPiece := range h.continuation[side] {
+			for previousTo := range h.continuation[side][previousPiece] {
+				for piece := range h.continuation[side][previousPiece][previousTo] {
+					for to := range h.continuation[side][previousPiece][previousTo][piece] {
+						h.continuation[side][previousPiece][previousTo][piece][to] /= 2
+					}
+				}
+			}
+		}
+	}
+}
+
+// updateHistory moves a history score towards the bound in the direction of the bonus, by less the
+// closer it already is
+func updateHistory(entry *int16, bonus int) {
+	value := int(*entry)
+	value += bonus - value*abs(bonus)/maxHistory
+	*entry = int16(value)
+}
+
+// historyBonus is how much a quiet move proving best at the depth changes its history
+func historyBonus(depth int) int {
+	return min(depth*depth, 1200)
+}
+
+// scoreMoves scores each move at the ply for ordering, in s.moveScores[ply]
+func (s *searcher) scoreMoves(moveList []chess.Move, ply int, hashMove chess.Move) {
+	board := &s.board
+	scores := s.moveScores[ply][:len(moveList)]
+	side := sideIndex(board.IsWhiteToMove())
+
+	var counterMove chess.Move
+	if ply > 0 && s.moveStack[ply-1] != 0 {
+		previous := s.moveStack[ply-1]
+		counterMove = s.history.counterMoves[previous.From()][previous.To()]
+	}
+
+	for i, move := range moveList {
+		switch {
+		case move == hashMove:
+			scores[i] = hashMoveScore
+
+		case move.IsPromotion() && move.PromotionPieceType() != chess.Queen:
+			scores[i] = badCaptureScore + mvvLva(board, move)
+
+		case move.IsPromotion() || board.IsCapture(move):
+			if staticExchange(board, move) >= 0 {
+				scores[i] = goodCaptureScore + mvvLva(board, move)
+			} else {
+				scores[i] = badCaptureScore + mvvLva(board, move)
+			}
+
+		case move == s.killers[ply][0]:
+			scores[i] = firstKillerScore
+
+		case move == s.killers[ply][1]:
+			scores[i] = secondKillerScore
+
+		case move == counterMove:
+			scores[i] = counterMoveScore
+
+		default:
+			piece, _ := board.PieceAt(int(move.From()))
+			scores[i] = s.quietScore(side, ply, piece, move)
+		}
+	}
+}
+
+// quietScore is the history of a quiet move, both on its own and as a reply to the opponent's last move
+func (s *searcher) quietScore(side int, ply int, piece int, move chess.Move) int {
+	score := int(s.history.butterfly[side][move.From()][move.To()])
+
+	if ply > 0 && s.moveStack[ply-1] != 0 {
+		previous := s.moveStack[ply-1]
+		score += int(s.history.continuation[side][s.movedPieces[ply-1]][previous.To()][piece][move.To()])
+	}
+
+	return score
+}
+
+// pickMove moves the best scoring of the moves from index onwards to index, so that moves are sorted
+// only as far as they are searched, which is often no further than the first
+func (s *searcher) pickMove(moveList []chess.Move, ply int, index int) {
+	scores := s.moveScores[ply][:len(moveList)]
+
+	best := index
+	for i := index + 1; i < len(moveList); i++ {
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+
+	moveList[index], moveList[best] = moveList[best], moveList[index]
+	scores[index], scores[best] = scores[best], scores[index]
+}
+
+// updateQuietHistory rewards a quiet move that proved best at the ply, and penalises the other quiet
+// moves searched before it
+func (s *searcher) updateQuietHistory(ply int, depth int, move chess.Move, quietsTried []chess.Move) {
+	board := &s.board
+	side := sideIndex(board.IsWhiteToMove())
+
+	if s.killers[ply][0] != move {
+		s.killers[ply][1] = s.killers[ply][0]
+		s.killers[ply][0] = move
+	}
+
+	if ply > 0 && s.moveStack[ply-1] != 0 {
+		previous := s.moveStack[ply-1]
+		s.history.counterMoves[previous.From()][previous.To()] = move
+	}
+
+	bonus := historyBonus(depth)
+	s.updateMoveHistory(side, ply, move, bonus)
+	for _, quiet := range quietsTried {
+		if quiet != move {
+			s.updateMoveHistory(side, ply, quiet, -bonus)
+		}
+	}
+}
+
+// updateMoveHistory changes the butterfly and continuation history of a quiet move
+func (s *searcher) updateMoveHistory(side int, ply int, move chess.Move, bonus int) {
+	updateHistory(&s.history.butterfly[side][move.From()][move.To()], bonus)
+
+	if ply > 0 && s.moveStack[ply-1] != 0 {
+		previous := s.moveStack[ply-1]
+		piece, _ := s.board.PieceAt(int(move.From()))
+		updateHistory(&s.history.continuation[side][s.movedPieces[ply-1]][previous.To()][piece][move.To()], bonus)
+	}
+}
+
+// mvvLva orders captures with the most valuable victims first, taken by the least valuable attackers
+func mvvLva(board *chess.Board, move chess.Move) int {
+	attacker, _ := board.PieceAt(int(move.From()))
+	return capturedValue(board, move)*8 - attacker
+}
+
+// isQuiet reports whether a move neither captures nor promotes
+func isQuiet(board *chess.Board, move chess.Move) bool {
+	return !move.IsPromotion() && !board.IsCapture(move)
+}
+
+// sideIndex indexes tables by the side to move
+func sideIndex(white bool) int {
+	return utility.If(white, 0, 1)
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	// Internal references
+	"goche/logger"
+	"goche/protection"
+)
+
+// The deepest a search goes when playing at limited strength
+const limitedStrengthDepth = 2
+
+// The outcome of the integrity check, and what to do if it failed. Tests stand in for a build that
+// fails the check.
+var (
+	integrityCheck  = protection.Result
+	integrityPolicy = protection.GetPolicy
+)
+
+// applyProtectionPolicy puts into effect what this build does when its integrity check has failed,
+// limiting the search's depth or refusing it. It returns true if the search is refused.
+func applyProtectionPolicy(limits *Limits) bool {
+	if integrityCheck() == nil {
+		return false
+	}
+
+	switch integrityPolicy() {
+	case protection.PolicyRefuse:
+		logger.Error("Search refused as the engine copy protection status is not ok")
+		return true
+
+	case protection.PolicyLimited:
+		if limits.Depth == 0 || limits.Depth > limitedStrengthDepth {
+			limits.Depth = limitedStrengthDepth
+		}
+	}
+
+	return false
+}
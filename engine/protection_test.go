@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	// Internal references
+	"goche/chess"
+	"goche/protection"
+)
+
+// standInIntegrityCheck stands in for a build whose integrity check has the result, with the policy
+func standInIntegrityCheck(t *testing.T, result error, policy protection.Policy) {
+	check, getPolicy := integrityCheck, integrityPolicy
+	t.Cleanup(func() {
+		integrityCheck, integrityPolicy = check, getPolicy
+	})
+
+	integrityCheck = func() error { return result }
+	integrityPolicy = func() protection.Policy { return policy }
+}
+
+// searchDepth searches to the depth, returning the deepest iteration reported and the best move
+func searchDepth(t *testing.T, e *Engine, depth int) (int, string) {
+	t.Helper()
+
+	reporter := startSearch(t, e, chess.FenStartingPosition, Limits{Depth: depth})
+	bestMove := <-reporter.Result
+
+	deepest := 0
+	for len(reporter.Progress) > 0 {
+		deepest = max(deepest, (<-reporter.Progress).Depth)
+	}
+
+	return deepest, bestMove.BestMove
+}
+
+func TestProtectionPolicies(t *testing.T) {
+	tests := []struct {
+		policy    protection.Policy
+		wantDepth int
+		refused   bool
+	}{
+		{protection.PolicyWarn, 5, false},
+		{protection.PolicyLimited, limitedStrengthDepth, false},
+		{protection.PolicyRefuse, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			standInIntegrityCheck(t, errors.New("executable does not match its manifest"), test.policy)
+
+			e := New()
+			setOption(t, e, "Threads", 2)
+
+			depth, bestMove := searchDepth(t, e, 5)
+			if depth != test.wantDepth {
+				t.Errorf("Searched to depth %d, want %d", depth, test.wantDepth)
+			}
+			if refused := bestMove == chess.NullMoveString; refused != test.refused {
+				t.Errorf("Best move %s, refused %t, want %t", bestMove, refused, test.refused)
+			}
+		})
+	}
+}
+
+func TestProtectionPassed(t *testing.T) {
+	// A build whose check passes plays at full strength, whatever its policy
+	standInIntegrityCheck(t, nil, protection.PolicyRefuse)
+
+	if depth, _ := searchDepth(t, New(), 5); depth != 5 {
+		t.Errorf("Searched to depth %d, want 5", depth)
+	}
+}
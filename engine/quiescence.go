@@ -0,0 +1,121 @@
+package engine
+
+import (
+	// Internal references
+	"goche/chess"
+)
+
+// A capture is not searched if winning the piece, plus this margin, would still leave the side to move
+// below alpha
+const deltaMargin = 200
+
+// quiesce searches captures and promotions beyond the main search's horizon, until the position is
+// quiet, so that it is not scored in the middle of an exchange. The side to move may instead stand
+// pat on the static evaluation, as it is rarely forced to capture. At the first ply the side to move
+// may be in check, when all of its moves are searched instead.
+func (s *searcher) quiesce(ply int, quiescePly int, alpha int, beta int) int {
+	s.pvLength[ply] = ply
+
+	s.checkLimits()
+	if s.aborted {
+		return 0
+	}
+	s.nodes.Add(1)
+	s.selDepth = max(s.selDepth, ply)
+
+	board := &s.board
+
+	if board.HalfMoveClock() >= 100 || s.isRepetition(ply) {
+		return 0
+	}
+
+	if ply >= maxPly {
+		return Evaluate(board)
+	}
+
+	bestScore := -infinity
+	var moveList []chess.Move
+
+	if quiescePly == 0 && board.IsInCheck() {
+		moveList, _ = board.GetMoves(s.moveLists[ply][:0])
+		if len(moveList) == 0 {
+			return -mateScore + ply
+		}
+	} else {
+		standPat := Evaluate(board)
+		if standPat >= beta {
+			return standPat
+		}
+
+		alpha = max(alpha, standPat)
+		bestScore = standPat
+
+		moveList, _ = board.GetCaptures(s.moveLists[ply][:0])
+		moveList = s.selectCaptures(moveList, ply, standPat, alpha)
+	}
+	s.moveLists[ply] = moveList
+
+	for _, move := range moveList {
+		undo := board.MakeMove(move)
+		s.hashes[ply+1] = board.GetHash()
+
+		score := -s.quiesce(ply+1, quiescePly+1, -beta, -alpha)
+
+		board.UnmakeMove(undo)
+
+		if s.aborted {
+			return 0
+		}
+
+		if score > bestScore {
+			bestScore = score
+		}
+
+		if score > alpha {
+			alpha = score
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+
+	return bestScore
+}
+
+// selectCaptures removes the captures not worth searching from the list, and orders the rest with the
+// most valuable victims first, taken by the least valuable attackers. Captures that cannot raise the
+// score to alpha, that lose material once the exchange is played out, or that promote to anything but a
+// queen are not worth searching.
+func (s *searcher) selectCaptures(moveList []chess.Move, ply int, standPat int, alpha int) []chess.Move {
+	board := &s.board
+	selected := moveList[:0]
+
+	for _, move := range moveList {
+		if move.IsPromotion() && move.PromotionPieceType() != chess.Queen {
+			continue
+		}
+
+		gain := capturedValue(board, move)
+		if !move.IsPromotion() && standPat+gain+deltaMargin <= alpha {
+			continue
+		}
+
+		if staticExchange(board, move) < 0 {
+			continue
+		}
+
+		s.moveScores[ply][len(selected)] = mvvLva(board, move)
+		selected = append(selected, move)
+	}
+
+	// There are few captures, so a simple insertion sort is quickest
+	scores := s.moveScores[ply][:len(selected)]
+	for i := 1; i < len(selected); i++ {
+		for j := i; j > 0 && scores[j] > scores[j-1]; j-- {
+			scores[j], scores[j-1] = scores[j-1], scores[j]
+			selected[j], selected[j-1] = selected[j-1], selected[j]
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,383 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/logger"
+	"goche/utility"
+)
+
+// Limits are the constraints on a search. Times are zero when not given; a search with no limits at
+// all runs until it is stopped.
+type Limits struct {
+	// Restricts the search to these moves, in UCI notation
+	SearchMoves []string
+
+	// Searches on the opponent's time, holding back the best move until PonderHit or Stop
+	Ponder bool
+
+	WhiteTime      time.Duration
+	BlackTime      time.Duration
+	WhiteIncrement time.Duration
+	BlackIncrement time.Duration
+	MovesToGo      int
+
+	// Set when the clocks are given, so that a clock at or below zero is told apart from no clock
+	Clock bool
+
+	Depth    int
+	Nodes    int
+	Mate     int
+	MoveTime time.Duration
+
+	// Searches until stopped, holding back the best move until then
+	Infinite bool
+}
+
+// SetClock sets the time left and increment of one side
+func (l *Limits) SetClock(white bool, timeLeft time.Duration, increment time.Duration) {
+	if white {
+		l.WhiteTime, l.WhiteIncrement = timeLeft, increment
+	} else {
+		l.BlackTime, l.BlackIncrement = timeLeft, increment
+	}
+	l.Clock = true
+}
+
+// Progress describes what a search has found so far
+type Progress struct {
+	Depth    int
+	SelDepth int
+	Score    utility.Score
+	Nodes    uint64
+	Elapsed  time.Duration
+	PV       []string
+
+	// How full the transposition table is, in permille
+	HashFull int
+
+	// The line's rank, from 1, when the MultiPV option asks for more than one line, and 0 otherwise
+	MultiPV int
+
+	// The move being searched at the root and its number, from 1, in the periodic reports made while
+	// an iteration is under way, which have no PV
+	CurrMove       string
+	CurrMoveNumber int
+
+	// How many nodes had a beta cutoff, and how many of those were on the first move searched, which
+	// measures how well moves are ordered
+	Cutoffs          uint64
+	FirstMoveCutoffs uint64
+}
+
+// Reporter receives the progress and result of a search, in the form required by the protocol in use.
+// It is called from the search's goroutine.
+type Reporter interface {
+	// Thinking reports the best line found so far
+	Thinking(progress Progress)
+
+	// BestMove reports the result of the search, which may be chess.NullMoveString if there is no legal move
+	BestMove(bestMove string, ponderMove string)
+}
+
+// CurrMoveReporter is a Reporter that also follows the move being searched at the root. The main
+// thread reports it from time to time during an iteration, throttled so as not to flood the GUI.
+type CurrMoveReporter interface {
+	Reporter
+
+	// SearchingMove reports the move being searched, and the nodes searched so far
+	SearchingMove(progress Progress)
+}
+
+// Result is the outcome of a search, as delivered by a ChannelReporter
+type Result struct {
+	BestMove   string
+	PonderMove string
+}
+
+// ChannelReporter delivers the progress and result of a search on channels. Progress is dropped
+// when its channel is full, so that a slow reader never holds up the search. Result receives exactly
+// one value, unless the search is stopped quietly.
+type ChannelReporter struct {
+	Progress chan Progress
+	Result   chan Result
+}
+
+// NewChannelReporter creates a reporter whose progress channel holds up to the given number of reports
+func NewChannelReporter(buffer int) ChannelReporter {
+	return ChannelReporter{
+		Progress: make(chan Progress, buffer),
+		Result:   make(chan Result, 1),
+	}
+}
+
+func (r ChannelReporter) Thinking(progress Progress) {
+	select {
+	case r.Progress <- progress:
+	default:
+	}
+}
+
+func (r ChannelReporter) BestMove(bestMove string, ponderMove string) {
+	r.Result <- Result{BestMove: bestMove, PonderMove: ponderMove}
+}
+
+// searcher runs a single search in its own goroutine, leaving the caller free to stop it, or to
+// tell it that the ponder move was played, while it works. The main thread may be joined by helper
+// threads, each a searcher of its own, which search the same position sharing the transposition table.
+// Only the main thread keeps time and reports.
+type searcher struct {
+	limits     Limits
+	board      chess.Board
+	parameters searchParameters
+	reductions *reductionTable
+	time       *TimeManager
+	tt         *transpositionTable
+	history    *moveHistory
+	reporter   Reporter
+
+	// The thread's number, which is 0 for the main thread, and the main thread's helpers
+	thread  int
+	helpers []*searcher
+
+	// Closed to ask the search to finish, with or without reporting a best move
+	stopSignal chan struct{}
+	stopOnce   sync.Once
+
+	// Closed when we are told the ponder move was played
+	ponderhitSignal chan struct{}
+	ponderhitOnce   sync.Once
+
+	// Closed by the search goroutine when it has finished, including reporting the best move
+	done chan struct{}
+
+	// Set when the search is not to be made, so that only the null move is reported
+	refused bool
+
+	mutex      sync.Mutex
+	quiet      bool
+	pondering  bool
+	clockStart time.Time
+
+	// Set when the search begins, regardless of pondering, for reporting the time searched
+	startTime time.Time
+
+	// Counted by the search goroutine, and read by the main thread for the total over all threads
+	nodes atomic.Uint64
+
+	// The state of the search itself, used only by the search goroutine
+	rootMoves      []chess.Move
+	depth          int
+	currMove       chess.Move
+	currMoveNumber int
+	selDepth       int
+	aborted        bool
+	completedDepth int
+	moveLists      [maxPly + 1][]chess.Move
+	hashes         [maxPly + 1]uint64
+	moveScores     [maxPly + 1][256]int
+
+	// The moves played to reach each ply, and the type of piece moved, for ordering replies to them
+	moveStack   [maxPly + 1]chess.Move
+	movedPieces [maxPly + 1]int
+
+	// Two quiet moves at each ply that recently caused a beta cutoff, and the quiet moves searched at
+	// each ply so far
+	killers     [maxPly + 1][2]chess.Move
+	quietsTried [maxPly + 1][maxQuietsTried]chess.Move
+
+	cutoffs          uint64
+	firstMoveCutoffs uint64
+
+	// Set while verifying a null move cutoff, when another null move would prove nothing
+	nullMoveDisabled bool
+
+	// The triangular principal variation table: row n holds the best line found from ply n
+	pvTable  [maxPly + 1][maxPly + 1]chess.Move
+	pvLength [maxPly + 1]int
+
+	// The number of lines to search from the root, and the one being searched, which leaves out the
+	// first moves of those before it
+	multiPV int
+	pvIndex int
+
+	// The lines found by the last completed iteration, best first
+	lines []rootLine
+
+	// Limits how often the main thread reports the move it is searching
+	throttle *utility.InfoThrottle
+}
+
+// newSearcher creates the search, with a thread for each of the move histories. The first is the
+// main thread's, and the threads after it are helpers.
+func newSearcher(limits Limits, position *chess.Board, parameters searchParameters, multiPV int, timeManager *TimeManager, tt *transpositionTable, histories []*moveHistory, reporter Reporter) *searcher {
+	s := &searcher{
+		limits:          limits,
+		board:           *position,
+		parameters:      parameters,
+		multiPV:         multiPV,
+		reductions:      newReductionTable(parameters),
+		time:            timeManager,
+		tt:              tt,
+		history:         histories[0],
+		reporter:        reporter,
+		stopSignal:      make(chan struct{}),
+		ponderhitSignal: make(chan struct{}),
+		done:            make(chan struct{}),
+		pondering:       limits.Ponder,
+		clockStart:      time.Now(),
+		startTime:       time.Now(),
+		throttle:        utility.NewInfoThrottle(utility.DefaultInfoDelay, utility.DefaultInfoInterval),
+	}
+
+	for thread, history := range histories[1:] {
+		s.helpers = append(s.helpers, s.newHelper(thread+1, history))
+	}
+
+	return s
+}
+
+// start launches the search goroutine
+func (s *searcher) start() {
+	go s.run()
+}
+
+// run is the body of the search goroutine
+func (s *searcher) run() {
+	defer close(s.done)
+
+	if !s.refused {
+		for _, helper := range s.helpers {
+			go helper.runHelper()
+		}
+
+		s.think()
+
+		// The helpers search for as long as the main thread does
+		for _, helper := range s.helpers {
+			helper.stop(false)
+		}
+	}
+
+	best := s.votedThread()
+
+	// While pondering or searching infinitely, the best move is withheld until it is released
+	s.waitForRelease()
+
+	s.mutex.Lock()
+	quiet := s.quiet
+	s.mutex.Unlock()
+
+	if quiet {
+		logger.Debug("Search ended without reporting a best move")
+		return
+	}
+
+	// The last line reported is to be the one played
+	if best != s {
+		s.reporter.Thinking(s.progress(best, 0))
+	}
+
+	s.reporter.BestMove(best.bestMove())
+}
+
+// isSearchMove reports whether the move may be searched, given any restriction to particular moves
+func (s *searcher) isSearchMove(move string) bool {
+	if len(s.limits.SearchMoves) == 0 {
+		return true
+	}
+
+	for _, searchMove := range s.limits.SearchMoves {
+		if searchMove == move {
+			return true
+		}
+	}
+
+	return false
+}
+
+// waitForRelease blocks while the best move must be held back, when pondering or searching infinitely
+func (s *searcher) waitForRelease() {
+	ponderhit := s.ponderhitSignal
+	for {
+		s.mutex.Lock()
+		holding := s.pondering || s.limits.Infinite
+		s.mutex.Unlock()
+
+		if !holding {
+			return
+		}
+
+		select {
+		case <-s.stopSignal:
+			return
+		case <-ponderhit:
+			// The signal stays closed, so an infinite search goes on to wait only to be stopped
+			ponderhit = nil
+		}
+	}
+}
+
+// stopped reports whether the search has been asked to finish
+func (s *searcher) stopped() bool {
+	select {
+	case <-s.stopSignal:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop asks the search to finish and waits for it to do so. A quiet stop suppresses the best move
+func (s *searcher) stop(quiet bool) {
+	if quiet {
+		s.mutex.Lock()
+		s.quiet = true
+		s.mutex.Unlock()
+	}
+
+	s.stopOnce.Do(func() {
+		close(s.stopSignal)
+	})
+
+	<-s.done
+}
+
+// ponderhit switches a pondering search over to normal time control, starting the clock from now
+func (s *searcher) ponderhit() {
+	s.mutex.Lock()
+	wasPondering := s.pondering
+	s.pondering = false
+	s.clockStart = time.Now()
+	s.mutex.Unlock()
+
+	if !wasPondering {
+		logger.Warn("Ponder hit when not pondering")
+	}
+
+	s.ponderhitOnce.Do(func() {
+		close(s.ponderhitSignal)
+	})
+}
+
+// finished reports whether the search goroutine has completed
+func (s *searcher) finished() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// runsIndefinitely reports whether the search would wait to be stopped before finishing
+func (s *searcher) runsIndefinitely() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.pondering || s.limits.Infinite
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	// Internal references
+	"goche/chess"
+)
+
+// cpuTime returns the processor time the test process has used
+func cpuTime(t *testing.T) time.Duration {
+	t.Helper()
+
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		t.Fatalf("Getrusage: %s", err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+func TestPonderhitInfiniteWaitsIdle(t *testing.T) {
+	e := New()
+
+	// The search reaches its depth at once, and then holds back its move until it is stopped
+	reporter := startSearch(t, e, chess.FenStartingPosition, Limits{Ponder: true, Infinite: true, Depth: 1})
+	time.Sleep(50 * time.Millisecond)
+
+	if !e.PonderHit() {
+		t.Fatal("No search to ponderhit")
+	}
+
+	start := cpuTime(t)
+	time.Sleep(300 * time.Millisecond)
+	if used := cpuTime(t) - start; used > 100*time.Millisecond {
+		t.Errorf("Used %s of CPU in 300ms waiting to be stopped", used)
+	}
+
+	select {
+	case result := <-reporter.Result:
+		t.Fatalf("Best move %s reported before stop", result.BestMove)
+	default:
+	}
+
+	e.Stop(false)
+	expectResult(t, e, reporter)
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"math"
+
+	// Internal references
+	"goche/chess"
+)
+
+// searchParameters switch the selective search techniques on and off and tune them. They are set
+// through hidden options, so that self-play can measure the effect of each.
+type searchParameters struct {
+	// Null move pruning: give the opponent a free move, and if a reduced search still fails high, so
+	// will a full one. The reduction grows with depth and with how far the evaluation is above beta.
+	nullMove             bool
+	nullMoveMinDepth     int
+	nullMoveReduction    int
+	nullMoveDepthDivisor int
+	nullMoveVerifyDepth  int
+
+	// Late move reductions: search quiet moves late in the ordering less deeply, by an amount in
+	// hundredths growing with the logarithms of the depth and move number
+	lateMoveReductions bool
+	lmrMinDepth        int
+	lmrBase            int
+	lmrDivisor         int
+
+	// Reverse futility pruning: fail high without searching when the evaluation is far above beta
+	reverseFutility       bool
+	reverseFutilityDepth  int
+	reverseFutilityMargin int
+
+	// Futility pruning: skip quiet moves when the evaluation is too far below alpha for them to help
+	futility       bool
+	futilityDepth  int
+	futilityMargin int
+
+	// Razoring: drop straight into the quiescence search when the evaluation is far below alpha
+	razoring       bool
+	razoringDepth  int
+	razoringMargin int
+
+	// Late move pruning: skip the quiet moves after a number that grows with depth
+	lateMovePruning bool
+	lmpDepth        int
+	lmpBase         int
+
+	// Internal iterative reductions: search less deeply at a node with no hash move to try first
+	internalIterativeReductions bool
+	iirMinDepth                 int
+}
+
+func defaultSearchParameters() searchParameters {
+	return searchParameters{
+		nullMove:             true,
+		nullMoveMinDepth:     3,
+		nullMoveReduction:    3,
+		nullMoveDepthDivisor: 6,
+		nullMoveVerifyDepth:  12,
+
+		lateMoveReductions: true,
+		lmrMinDepth:        3,
+		lmrBase:            75,
+		lmrDivisor:         225,
+
+		reverseFutility:       true,
+		reverseFutilityDepth:  8,
+		reverseFutilityMargin: 80,
+
+		futility:       true,
+		futilityDepth:  6,
+		futilityMargin: 100,
+
+		razoring:       true,
+		razoringDepth:  2,
+		razoringMargin: 300,
+
+		lateMovePruning: true,
+		lmpDepth:        8,
+		lmpBase:         3,
+
+		internalIterativeReductions: true,
+		iirMinDepth:                 4,
+	}
+}
+
+// The size of the late move reduction table, beyond which depths and move numbers share the last entry
+const reductionTableSize = 64
+
+// reductionTable holds the late move reduction for each depth and move number
+type reductionTable [reductionTableSize][reductionTableSize]int
+
+func newReductionTable(p searchParameters) *reductionTable {
+	var table reductionTable
+
+	for depth := 1; depth < reductionTableSize; depth++ {
+		for moveNumber := 1; moveNumber < reductionTableSize; moveNumber++ {
+			reduction := float64(p.lmrBase)/100 + math.Log(float64(depth))*math.Log(float64(moveNumber))*100/float64(p.lmrDivisor)
+			table[depth][moveNumber] = int(reduction)
+		}
+	}
+
+	return &table
+}
+
+// reduction returns the late move reduction for a move searched at the depth after moveNumber others
+func (t *reductionTable) reduction(depth int, moveNumber int) int {
+	return t[min(depth, reductionTableSize-1)][min(moveNumber, reductionTableSize-1)]
+}
+
+// lateMoveCount returns the number of quiet moves searched at the depth before the rest are pruned
+func (p searchParameters) lateMoveCount(depth int) int {
+	return p.lmpBase + depth*depth
+}
+
+// nullMoveReductionFor returns how much less deeply to search after a null move
+func (p searchParameters) nullMoveReductionFor(depth int, staticEval int, beta int) int {
+	return p.nullMoveReduction + depth/p.nullMoveDepthDivisor + min((staticEval-beta)/200, 3)
+}
+
+// hasNonPawnMaterial reports whether the side has a piece other than its king and pawns. Without one,
+// zugzwang is common, and passing is no guide to the value of moving.
+func hasNonPawnMaterial(board *chess.Board, white bool) bool {
+	return board.Pieces(chess.Knight, white)|board.Pieces(chess.Bishop, white)|
+		board.Pieces(chess.Rook, white)|board.Pieces(chess.Queen, white) != 0
+}
+
+// pruneNode tries to settle a node that is not on the principal variation, and not in check, without
+// searching its moves, using the static evaluation. It returns the score and whether it succeeded.
+func (s *searcher) pruneNode(depth int, ply int, alpha int, beta int, staticEval int) (int, bool) {
+	p := &s.parameters
+	board := &s.board
+
+	// So far above beta that no reply is likely to bring the score back down
+	if p.reverseFutility && depth <= p.reverseFutilityDepth && abs(beta) < mateThreshold &&
+		staticEval-p.reverseFutilityMargin*depth >= beta {
+		return staticEval, true
+	}
+
+	// So far below alpha that only a capture could help, which the quiescence search will find
+	if p.razoring && depth <= p.razoringDepth && staticEval+p.razoringMargin*depth <= alpha {
+		score := s.quiesce(ply, 0, alpha, beta)
+		if s.aborted || score <= alpha {
+			return score, true
+		}
+	}
+
+	// Two null moves in a row would prove nothing, and neither would one in a pawn ending
+	if p.nullMove && !s.nullMoveDisabled && depth >= p.nullMoveMinDepth && staticEval >= beta &&
+		s.moveStack[ply-1] != 0 && hasNonPawnMaterial(board, board.IsWhiteToMove()) {
+		reduction := p.nullMoveReductionFor(depth, staticEval, beta)
+
+		s.moveStack[ply] = 0
+		undo := board.MakeNullMove()
+		s.hashes[ply+1] = board.GetHash()
+
+		score := -s.negamax(depth-1-reduction, ply+1, -beta, -beta+1)
+
+		board.UnmakeMove(undo)
+
+		if s.aborted {
+			return 0, true
+		}
+
+		if score >= beta {
+			// A mate found after passing is not to be trusted
+			score = min(score, mateThreshold-1)
+			if depth < p.nullMoveVerifyDepth {
+				return score, true
+			}
+
+			// Deep in the tree a missed zugzwang costs too much, so confirm the cutoff with a reduced
+			// search in which the side to move has to move
+			s.nullMoveDisabled = true
+			verified := s.negamax(depth-1-reduction, ply, beta-1, beta)
+			s.nullMoveDisabled = false
+
+			if s.aborted || verified >= beta {
+				return score, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// lateMoveReduction returns how much less deeply to search a late quiet move at first, which is less
+// on the principal variation and for moves with a good history
+func (s *searcher) lateMoveReduction(depth int, ply int, moveNumber int, pvNode bool) int {
+	reduction := s.reductions.reduction(depth, moveNumber)
+
+	if pvNode {
+		reduction--
+	}
+
+	// Killers and countermoves score above any history
+	moveScore := s.moveScores[ply][moveNumber]
+	if moveScore >= counterMoveScore {
+		reduction--
+	} else {
+		reduction -= moveScore / 8192
+	}
+
+	return max(0, min(reduction, depth-2))
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	// Internal references
+	"goche/chess"
+)
+
+// The most threads the Threads option allows
+const maxThreads = 256
+
+// Helper threads skip some of the depths of the iterative deepening, so that they spread over several
+// depths rather than all searching the one the main thread is. A helper uses the entry of these tables
+// for its number, and skips the depths at which (depth + phase) / size is odd.
+var (
+	skipSizes  = [...]int{1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4, 4, 4, 4}
+	skipPhases = [...]int{0, 1, 0, 1, 2, 3, 0, 1, 2, 3, 4, 5, 0, 1, 2, 3, 4, 5, 6, 7}
+)
+
+// Added to every thread's score in the vote, so that the thread with the lowest score still counts
+const voteBase = 14
+
+// newHelper creates a helper thread, which searches the same position within the same limits, sharing
+// the transposition table, but with its own move history
+func (s *searcher) newHelper(thread int, history *moveHistory) *searcher {
+	return &searcher{
+		limits:     s.limits,
+		board:      s.board,
+		parameters: s.parameters,
+		multiPV:    1,
+		reductions: s.reductions,
+		tt:         s.tt,
+		history:    history,
+		thread:     thread,
+		stopSignal: make(chan struct{}),
+		done:       make(chan struct{}),
+		startTime:  s.startTime,
+	}
+}
+
+// runHelper is the body of a helper thread's goroutine
+func (s *searcher) runHelper() {
+	defer close(s.done)
+
+	s.think()
+}
+
+// skipsDepth reports whether the thread leaves out the depth of the iterative deepening
+func (s *searcher) skipsDepth(depth int) bool {
+	if s.thread == 0 {
+		return false
+	}
+
+	i := (s.thread - 1) % len(skipSizes)
+	return (depth+skipPhases[i])/skipSizes[i]%2 != 0
+}
+
+// totalNodes returns the nodes searched by the main thread and its helpers
+func (s *searcher) totalNodes() uint64 {
+	nodes := s.nodes.Load()
+	for _, helper := range s.helpers {
+		nodes += helper.nodes.Load()
+	}
+	return nodes
+}
+
+// votedThread chooses the thread whose move to play, once the helpers have finished. Each thread votes
+// for its best move, with a weight growing with the depth it completed and its score. A mate found by
+// any thread is played, as it is proven, however few votes it has. Helpers search a single line, so
+// when there are several the main thread's are kept.
+func (s *searcher) votedThread() *searcher {
+	best := s
+	if len(s.helpers) == 0 || s.multiPV > 1 {
+		return best
+	}
+
+	threads := append([]*searcher{s}, s.helpers...)
+
+	minScore := infinity
+	for _, thread := range threads {
+		if thread.completedDepth > 0 {
+			minScore = min(minScore, thread.lines[0].score)
+		}
+	}
+
+	votes := make(map[chess.Move]int)
+	for _, thread := range threads {
+		if thread.completedDepth > 0 {
+			votes[thread.lines[0].pv[0]] += (thread.lines[0].score - minScore + voteBase) * thread.completedDepth
+		}
+	}
+
+	for _, thread := range s.helpers {
+		if thread.completedDepth == 0 {
+			continue
+		}
+
+		switch {
+		// Once a mate is found either way, only a quicker mate or a slower defeat is better
+		case abs(best.lines[0].score) >= mateThreshold:
+			if thread.lines[0].score > best.lines[0].score {
+				best = thread
+			}
+		case thread.lines[0].score >= mateThreshold,
+			thread.lines[0].score > -mateThreshold && votes[thread.lines[0].pv[0]] > votes[best.lines[0].pv[0]]:
+			best = thread
+		}
+	}
+
+	return best
+}
+
+// bestMove returns the first move of the thread's best line, and the move it expects in reply
+func (s *searcher) bestMove() (string, string) {
+	if len(s.lines) == 0 {
+		return chess.NullMoveString, ""
+	}
+
+	pv := s.lines[0].pv
+	ponderMove := ""
+	if len(pv) > 1 {
+		ponderMove = pv[1].ToUciString()
+	}
+
+	return pv[0].ToUciString(), ponderMove
+}
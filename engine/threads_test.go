@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	// Internal references
+	"goche/chess"
+)
+
+// startSearch searches the position with the engine, returning the reporter that receives the result
+func startSearch(t *testing.T, e *Engine, fen string, limits Limits) ChannelReporter {
+	t.Helper()
+
+	board, err := chess.NewBoard(fen)
+	if err != nil {
+		t.Fatalf("NewBoard: %s", err)
+	}
+	e.SetPosition(board)
+
+	reporter := NewChannelReporter(1024)
+	e.Go(limits, reporter)
+	return reporter
+}
+
+// expectResult waits for the search's best move, failing unless it is legal in the position searched
+func expectResult(t *testing.T, e *Engine, reporter ChannelReporter) string {
+	t.Helper()
+
+	select {
+	case result := <-reporter.Result:
+		if _, err := e.Position().FindMove(result.BestMove); err != nil {
+			t.Fatalf("Best move %s: %s", result.BestMove, err)
+		}
+		return result.BestMove
+	case <-time.After(30 * time.Second):
+		t.Fatal("No best move reported")
+	}
+
+	return ""
+}
+
+func setOption(t *testing.T, e *Engine, name string, value int) {
+	t.Helper()
+
+	if err := e.SetOption(name, strconv.Itoa(value)); err != nil {
+		t.Fatalf("Setting %s to %d: %s", name, value, err)
+	}
+}
+
+func TestThreadsStop(t *testing.T) {
+	e := New()
+	setOption(t, e, "Threads", 4)
+
+	// Stopping reports the best move found by the threads so far
+	reporter := startSearch(t, e, chess.FenStartingPosition, Limits{Infinite: true})
+	time.Sleep(100 * time.Millisecond)
+	e.Stop(false)
+	expectResult(t, e, reporter)
+
+	// Stopping quietly, as 'quit' does, ends the search without a best move
+	reporter = startSearch(t, e, chess.FenStartingPosition, Limits{Infinite: true})
+	time.Sleep(100 * time.Millisecond)
+	e.Stop(true)
+
+	if e.Searching() {
+		t.Error("Still searching after a quiet stop")
+	}
+	select {
+	case result := <-reporter.Result:
+		t.Errorf("Quiet stop reported best move %s", result.BestMove)
+	default:
+	}
+
+	// A search started while another is running stops it first
+	first := startSearch(t, e, chess.FenStartingPosition, Limits{Infinite: true})
+	time.Sleep(50 * time.Millisecond)
+	second := startSearch(t, e, chess.FenStartingPosition, Limits{Depth: 4})
+	expectResult(t, e, first)
+	expectResult(t, e, second)
+}
+
+func TestThreadsOptionChanges(t *testing.T) {
+	e := New()
+
+	// The options change between searches, each search voting among the threads it was started with
+	changes := []struct {
+		name  string
+		value int
+	}{
+		{"Threads", 3},
+		{"Hash", 2},
+		{"MultiPV", 2},
+		{"Threads", 1},
+		{"MultiPV", 1},
+		{"Threads", 5},
+		{"Hash", 1},
+		{"Threads", 2},
+	}
+
+	for _, change := range changes {
+		setOption(t, e, change.name, change.value)
+
+		reporter := startSearch(t, e, chess.FenStartingPosition, Limits{Depth: 5})
+		expectResult(t, e, reporter)
+
+		// A search stopped part of the way through its first iteration still has a move to play
+		reporter = startSearch(t, e, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", Limits{Infinite: true})
+		e.Stop(false)
+		expectResult(t, e, reporter)
+	}
+
+	// Changing the threads during a search affects only the next one
+	reporter := startSearch(t, e, chess.FenStartingPosition, Limits{Infinite: true})
+	time.Sleep(50 * time.Millisecond)
+	setOption(t, e, "Threads", 4)
+	setOption(t, e, "Hash", 4)
+	e.NewGame()
+	e.Stop(false)
+	expectResult(t, e, reporter)
+}
+
+func TestThreadsFindMate(t *testing.T) {
+	e := New()
+	setOption(t, e, "Threads", 4)
+
+	// However the votes fall, a mate found by any thread is played
+	reporter := startSearch(t, e, "6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1", Limits{Depth: 6})
+	if bestMove := expectResult(t, e, reporter); bestMove != "d1d8" {
+		t.Errorf("Best move %s, want d1d8", bestMove)
+	}
+}
+
+func TestThreadsConcurrentGo(t *testing.T) {
+	e := New()
+	setOption(t, e, "Threads", 2)
+
+	// Each search started replaces the one before, however close together they are started, so once
+	// the last is stopped every search has reported
+	reporters := make([]ChannelReporter, 8)
+	var started sync.WaitGroup
+	for i := range reporters {
+		reporters[i] = NewChannelReporter(1024)
+		started.Add(1)
+		go func(reporter ChannelReporter) {
+			defer started.Done()
+			e.Go(Limits{Infinite: true}, reporter)
+		}(reporters[i])
+	}
+	started.Wait()
+
+	time.Sleep(50 * time.Millisecond)
+	e.Stop(false)
+
+	for i, reporter := range reporters {
+		select {
+		case <-reporter.Result:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Search %d never reported, so was left running", i)
+		}
+	}
+	if e.Searching() {
+		t.Error("Still searching after the last search was stopped")
+	}
+}
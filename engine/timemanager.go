@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/utility"
+)
+
+// The time, in milliseconds, allowed for each move to reach the GUI, as set by the Move Overhead option
+const (
+	defaultMoveOverhead = 30
+	maxMoveOverhead     = 5000
+)
+
+// The least time a search is given, however little is left on the clock
+const minimumThinkTime = time.Millisecond
+
+// The most a search is given when the clock shows no time left, however large the increment
+const noTimeLeftCap = 100 * time.Millisecond
+
+// The number of moves the remaining time is shared between when the time control does not say, from the
+// opening to the endgame, and the most that are allowed for when it does
+const (
+	openingMovesLeft = 45
+	endgameMovesLeft = 20
+	maxMovesToGo     = 50
+)
+
+// TimeManager decides how long a search may use once our clock is running. It aims to use an optimum
+// time, which it stretches while the best move keeps changing or the score is falling, and shrinks while
+// the best move is stable, and it abandons the search at a maximum time. Zero means no limit.
+//
+// It is given the time elapsed rather than reading a clock, so that it can be driven by a simulated
+// clock as well as by a search.
+type TimeManager struct {
+	optimum time.Duration
+	maximum time.Duration
+
+	// What the iterations so far have found
+	iterations       int
+	previousBest     chess.Move
+	previousScore    int
+	bestMoveChanges  float64
+	stableIterations int
+	scoreDrop        int
+}
+
+// NewTimeManager shares out the time on the clock of the side to move in the position. The move
+// overhead is kept back from every move, and more time is allowed when the opponent's thinking time may
+// be used for pondering.
+func NewTimeManager(limits Limits, position *chess.Board, moveOverhead time.Duration, ponder bool) *TimeManager {
+	t := &TimeManager{}
+
+	if limits.Infinite {
+		return t
+	}
+
+	// A fixed time per move is used in full, as there is no later move to save it for
+	if limits.MoveTime > 0 {
+		t.maximum = max(limits.MoveTime-moveOverhead, minimumThinkTime)
+		return t
+	}
+
+	white := position.IsWhiteToMove()
+	timeLeft := utility.If(white, limits.WhiteTime, limits.BlackTime)
+	increment := utility.If(white, limits.WhiteIncrement, limits.BlackIncrement)
+	if !limits.Clock {
+		return t
+	}
+
+	// With nothing left on the clock, or less than nothing once flagged, move almost at once. Only
+	// the increment can pay for any thought.
+	if timeLeft <= 0 {
+		t.maximum = min(max(increment-moveOverhead, minimumThinkTime), noTimeLeftCap)
+		t.optimum = t.maximum
+		return t
+	}
+
+	available := max(timeLeft-moveOverhead, 0)
+
+	// More moves remain to be played in the opening than in the endgame
+	movesToGo := limits.MovesToGo
+	if movesToGo <= 0 {
+		phase := gamePhase(position)
+		movesToGo = endgameMovesLeft + (openingMovesLeft-endgameMovesLeft)*phase/totalPhase
+	}
+	movesToGo = min(movesToGo, maxMovesToGo)
+
+	optimum := available/time.Duration(movesToGo) + increment*3/4
+	if ponder {
+		optimum += optimum / 4
+	}
+
+	// Never use so much on one move that too little is left for the rest
+	t.maximum = max(min(optimum*4, available*3/4), minimumThinkTime)
+	t.optimum = max(min(optimum, t.maximum), minimumThinkTime)
+
+	return t
+}
+
+// Update records the best move and score found by an iteration
+func (t *TimeManager) Update(bestMove chess.Move, score utility.Score) {
+	// Any mate counts as far beyond any score in centipawns
+	value := score.Value
+	if score.Mate {
+		value = utility.If(score.Value > 0, mateThreshold, -mateThreshold)
+	}
+
+	// Changes of mind count for less the longer ago they were
+	t.bestMoveChanges /= 2
+
+	if t.iterations > 0 {
+		if bestMove != t.previousBest {
+			t.bestMoveChanges++
+			t.stableIterations = 0
+		} else {
+			t.stableIterations++
+		}
+		t.scoreDrop = t.previousScore - value
+	}
+
+	t.iterations++
+	t.previousBest = bestMove
+	t.previousScore = value
+}
+
+// Optimum returns the time the search aims to use before any iterations have been recorded
+func (t *TimeManager) Optimum() time.Duration {
+	return t.optimum
+}
+
+// Maximum returns the time at which the search is abandoned
+func (t *TimeManager) Maximum() time.Duration {
+	return t.maximum
+}
+
+// Target returns the time the search should aim to use, given what the iterations so far have found
+func (t *TimeManager) Target() time.Duration {
+	factor := 1 + t.bestMoveChanges/2
+	factor *= max(0.6, 1-0.1*float64(t.stableIterations))
+	if t.scoreDrop > 0 {
+		factor *= 1 + float64(min(t.scoreDrop, 150))/300
+	}
+
+	return min(time.Duration(float64(t.optimum)*factor), t.maximum)
+}
+
+// Timed reports whether the search is to be fitted to the clock, rather than given a fixed time or none
+func (t *TimeManager) Timed() bool {
+	return t.optimum > 0
+}
+
+// StartIteration reports whether another iteration is worth starting, after the time elapsed. The next
+// iteration would probably take longer than all those before it, and would not finish in time.
+func (t *TimeManager) StartIteration(elapsed time.Duration) bool {
+	return !t.Timed() || elapsed < t.Target()/2
+}
+
+// Expired reports whether the search must be abandoned, after the time elapsed
+func (t *TimeManager) Expired(elapsed time.Duration) bool {
+	return t.maximum > 0 && elapsed >= t.maximum
+}
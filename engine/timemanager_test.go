@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	// Internal references
+	"goche/chess"
+)
+
+func TestTimeManagerNoTimeLeft(t *testing.T) {
+	board, _ := chess.NewBoard(chess.FenStartingPosition)
+	overhead := defaultMoveOverhead * time.Millisecond
+
+	tests := []struct {
+		name      string
+		timeLeft  time.Duration
+		increment time.Duration
+		want      time.Duration
+	}{
+		{"zero", 0, 0, minimumThinkTime},
+		{"negative", -50 * time.Millisecond, 0, minimumThinkTime},
+		{"zero with increment", 0, 80 * time.Millisecond, 50 * time.Millisecond},
+		{"negative with large increment", -time.Second, 5 * time.Second, noTimeLeftCap},
+		{"increment within the overhead", 0, 10 * time.Millisecond, minimumThinkTime},
+	}
+
+	for _, test := range tests {
+		limits := Limits{}
+		limits.SetClock(true, test.timeLeft, test.increment)
+		limits.SetClock(false, time.Second, test.increment)
+
+		manager := NewTimeManager(limits, board, overhead, false)
+		if !manager.Timed() || manager.Maximum() != test.want || manager.Optimum() != test.want {
+			t.Errorf("%s: timed %t, optimum %s, maximum %s, want %s", test.name, manager.Timed(), manager.Optimum(), manager.Maximum(), test.want)
+		}
+	}
+
+	// Without a clock at all, the search is not limited by time
+	if manager := NewTimeManager(Limits{Depth: 5}, board, overhead, false); manager.Timed() || manager.Maximum() != 0 {
+		t.Errorf("No clock: timed %t, maximum %s", manager.Timed(), manager.Maximum())
+	}
+}
+
+func TestSearchWithNoTimeLeft(t *testing.T) {
+	e := New()
+
+	limits := Limits{}
+	limits.SetClock(true, -50*time.Millisecond, 0)
+	limits.SetClock(false, time.Second, 0)
+
+	start := time.Now()
+	expectResult(t, e, startSearch(t, e, chess.FenStartingPosition, limits))
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Searched for %s with no time left", elapsed)
+	}
+}
@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"sync/atomic"
+
+	// Internal references
+	"goche/chess"
+)
+
+// The size of the transposition table, in megabytes, as set by the Hash option
+const (
+	defaultHashSize = 16
+	minHashSize     = 1
+	maxHashSize     = 32768
+)
+
+// Bounds of a score held in the transposition table
+const (
+	boundNone uint8 = iota
+	boundExact
+	boundLower
+	boundUpper
+)
+
+// Entries are packed into 64 bits of data, alongside the position's hash:
+//
+//	bits  0-15  best move
+//	bits 16-31  score, as a signed 16-bit number
+//	bits 32-39  depth
+//	bits 40-41  bound
+//	bits 42-47  age, the number of the search that stored it
+const (
+	scoreShift = 16
+	depthShift = 32
+	boundShift = 40
+	ageShift   = 42
+	ageMask    = 0x3f
+)
+
+// The number of entries in a bucket, which fills a 64-byte cache line
+const bucketSize = 4
+
+// The number of buckets sampled to estimate how full the table is
+const hashFullSample = 250
+
+// ttEntry holds the hash exclusive-ored with the data rather than the hash itself, so that an entry
+// torn by two threads writing at once does not verify and is ignored rather than trusted
+type ttEntry struct {
+	check uint64
+	data  uint64
+}
+
+type ttBucket [bucketSize]ttEntry
+
+// transpositionTable remembers the results of searching positions, so that they need not be searched
+// again when reached by another move order, or in the next iteration. It may be shared between searches
+// running at once without locking.
+type transpositionTable struct {
+	buckets []ttBucket
+	mask    uint64
+	age     uint8
+}
+
+// ttProbe is what the table knows about a position
+type ttProbe struct {
+	move  chess.Move
+	score int
+	depth int
+	bound uint8
+}
+
+// newTranspositionTable creates a table of the given size in megabytes, rounded down to a power of
+// two number of buckets
+func newTranspositionTable(megabytes int) *transpositionTable {
+	bucketBytes := uint64(bucketSize * 16)
+	count := uint64(1)
+	for count*2*bucketBytes <= uint64(megabytes)<<20 {
+		count *= 2
+	}
+
+	return &transpositionTable{
+		buckets: make([]ttBucket, count),
+		mask:    count - 1,
+	}
+}
+
+// clear empties the table
+func (tt *transpositionTable) clear() {
+	for i := range tt.buckets {
+		for j := range tt.buckets[i] {
+			atomic.StoreUint64(&tt.buckets[i][j].check, 0)
+			atomic.StoreUint64(&tt.buckets[i][j].data, 0)
+		}
+	}
+}
+
+// newSearch ages the entries already stored, so that they are replaced in preference to new ones.
+// It must not be called while a search is using the table.
+func (tt *transpositionTable) newSearch() {
+	tt.age = (tt.age + 1) & ageMask
+}
+
+// probe looks up a position, adjusting mate scores to be relative to the ply at which it is reached
+func (tt *transpositionTable) probe(hash uint64, ply int) (ttProbe, bool) {
+	bucket := &tt.buckets[hash&tt.mask]
+
+	for i := range bucket {
+		data := atomic.LoadUint64(&bucket[i].data)
+		if atomic.LoadUint64(&bucket[i].check)^data != hash || data == 0 {
+			continue
+		}
+
+		return ttProbe{
+			move:  chess.Move(data),
+			score: scoreFromTable(int(int16(data>>scoreShift)), ply),
+			depth: int(uint8(data >> depthShift)),
+			bound: uint8(data>>boundShift) & 0b11,
+		}, true
+	}
+
+	return ttProbe{}, false
+}
+
+// store records the result of searching a position. It replaces the entry for the same position, or
+// else the one in the bucket least worth keeping, preferring to keep deep results from recent searches.
+func (tt *transpositionTable) store(hash uint64, ply int, move chess.Move, score int, depth int, bound uint8) {
+	bucket := &tt.buckets[hash&tt.mask]
+
+	replace := 0
+	lowestWorth := 1 << 30
+
+	for i := range bucket {
+		data := atomic.LoadUint64(&bucket[i].data)
+		if data == 0 {
+			replace = i
+			break
+		}
+
+		if atomic.LoadUint64(&bucket[i].check)^data == hash {
+			// Keep the best move already known if this result has none
+			if move == 0 {
+				move = chess.Move(data)
+			}
+			replace = i
+			break
+		}
+
+		age := (tt.age - uint8(data>>ageShift)) & ageMask
+		worth := int(uint8(data>>depthShift)) - 8*int(age)
+		if worth < lowestWorth {
+			lowestWorth = worth
+			replace = i
+		}
+	}
+
+	data := uint64(move) |
+		uint64(uint16(int16(scoreToTable(score, ply))))<<scoreShift |
+		uint64(uint8(max(depth, 0)))<<depthShift |
+		uint64(bound)<<boundShift |
+		uint64(tt.age)<<ageShift
+
+	atomic.StoreUint64(&bucket[replace].data, data)
+	atomic.StoreUint64(&bucket[replace].check, hash^data)
+}
+
+// hashFull estimates how full the table is with entries from the current search, in permille
+func (tt *transpositionTable) hashFull() int {
+	sample := min(hashFullSample, len(tt.buckets))
+
+	used := 0
+	for i := 0; i < sample; i++ {
+		for j := range tt.buckets[i] {
+			data := atomic.LoadUint64(&tt.buckets[i][j].data)
+			if data != 0 && uint8(data>>ageShift)&ageMask == tt.age {
+				used++
+			}
+		}
+	}
+
+	return used * 1000 / (sample * bucketSize)
+}
+
+// scoreToTable converts a mate score from plies to mate from the root to plies to mate from the
+// position, which is the same wherever the position is reached
+func scoreToTable(score int, ply int) int {
+	switch {
+	case score >= mateThreshold:
+		return score + ply
+	case score <= -mateThreshold:
+		return score - ply
+	}
+	return score
+}
+
+// scoreFromTable converts a mate score from the table back to plies to mate from the root
+func scoreFromTable(score int, ply int) int {
+	switch {
+	case score >= mateThreshold:
+		return score - ply
+	case score <= -mateThreshold:
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,364 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+	"goche/logger"
+	"goche/utility"
+)
+
+// How long a finished analysis is kept, so that a client can still collect its events
+const finishedRetention = time.Minute
+
+// errAnalysisNotFound is returned for an unknown analysis id
+var errAnalysisNotFound = errors.New("analysis not found")
+
+// analysisRequest is the body of POST /api/analysis. Without a depth or move time the analysis runs
+// until it is stopped.
+type analysisRequest struct {
+	FEN        string   `json:"fen"`
+	Moves      []string `json:"moves"`
+	Depth      int      `json:"depth"`
+	MoveTimeMs int      `json:"moveTimeMs"`
+}
+
+type analysisResponse struct {
+	ID string `json:"id"`
+}
+
+// scoreResponse is a score in centipawns or moves to mate, from the point of view of the side to move
+type scoreResponse struct {
+	Centipawns *int   `json:"cp,omitempty"`
+	Mate       *int   `json:"mate,omitempty"`
+	Bound      string `json:"bound,omitempty"`
+}
+
+// infoEvent is sent as the analysis progresses
+type infoEvent struct {
+	Depth  int           `json:"depth"`
+	Score  scoreResponse `json:"score"`
+	Nodes  uint64        `json:"nodes"`
+	TimeMs int64         `json:"timeMs"`
+	PV     []string      `json:"pv"`
+}
+
+// bestMoveEvent is the last event of an analysis
+type bestMoveEvent struct {
+	BestMove string `json:"bestMove"`
+	Ponder   string `json:"ponder,omitempty"`
+}
+
+// event is a Server-Sent Event, with a JSON body
+type event struct {
+	name string
+	data interface{}
+}
+
+// analysis is a search running on behalf of HTTP clients. Its events are kept so that a client that
+// connects to the stream late still receives them all.
+type analysis struct {
+	id     string
+	engine *engine.Engine
+
+	mutex    sync.Mutex
+	events   []event
+	changed  chan struct{}
+	finished bool
+}
+
+// Thinking records the progress of the search as an 'info' event
+func (a *analysis) Thinking(progress engine.Progress) {
+	if len(progress.PV) == 0 {
+		return
+	}
+
+	a.publish(event{name: "info", data: infoEvent{
+		Depth:  progress.Depth,
+		Score:  newScoreResponse(progress.Score),
+		Nodes:  progress.Nodes,
+		TimeMs: progress.Elapsed.Milliseconds(),
+		PV:     progress.PV,
+	}}, false)
+}
+
+// BestMove records the result of the search as a 'bestmove' event, which ends the stream
+func (a *analysis) BestMove(bestMove string, ponderMove string) {
+	a.publish(event{name: "bestmove", data: bestMoveEvent{BestMove: bestMove, Ponder: ponderMove}}, true)
+}
+
+// publish adds an event and wakes any streams waiting for it
+func (a *analysis) publish(e event, last bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.events = append(a.events, e)
+	a.finished = a.finished || last
+
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// next returns the events from the given index, a channel that is closed when there are more, and
+// whether the analysis has finished
+func (a *analysis) next(from int) ([]event, <-chan struct{}, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.events[from:], a.changed, a.finished
+}
+
+// analyses holds the analyses that are running, or finished recently. Perfts share the limit on how
+// many may run at once, as they tie up a CPU in the same way.
+type analyses struct {
+	maxRunning int
+
+	mutex  sync.Mutex
+	byID   map[string]*analysis
+	nextID int
+	perfts int
+}
+
+func newAnalyses(maxRunning int) *analyses {
+	return &analyses{
+		maxRunning: maxRunning,
+		byID:       make(map[string]*analysis),
+		nextID:     1,
+	}
+}
+
+// start begins a new analysis of the position, unless too many are running already
+func (as *analyses) start(board *chess.Board, limits engine.Limits) (*analysis, error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	if running := as.running(); running >= as.maxRunning {
+		return nil, fmt.Errorf("%d analyses are already running", running)
+	}
+
+	// Each analysis has an engine of its own, so that they run independently
+	a := &analysis{
+		id:      strconv.Itoa(as.nextID),
+		engine:  engine.New(),
+		changed: make(chan struct{}),
+	}
+	as.nextID++
+	as.byID[a.id] = a
+
+	a.engine.SetPosition(board)
+	a.engine.Go(limits, a)
+
+	// Forget the analysis a while after it finishes
+	go func() {
+		a.engine.Wait()
+		time.AfterFunc(finishedRetention, func() {
+			as.remove(a.id)
+		})
+	}()
+
+	return a, nil
+}
+
+// startPerft takes one of the places of the running analyses for a perft, which must call the
+// function returned when it is done
+func (as *analyses) startPerft() (func(), error) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	if running := as.running(); running >= as.maxRunning {
+		return nil, fmt.Errorf("%d analyses are already running", running)
+	}
+	as.perfts++
+
+	return func() {
+		as.mutex.Lock()
+		defer as.mutex.Unlock()
+
+		as.perfts--
+	}, nil
+}
+
+// running counts the analyses and perfts that have not finished. The caller must hold the mutex.
+func (as *analyses) running() int {
+	running := as.perfts
+	for _, a := range as.byID {
+		a.mutex.Lock()
+		if !a.finished {
+			running++
+		}
+		a.mutex.Unlock()
+	}
+
+	return running
+}
+
+// get returns the analysis with the id, or nil
+func (as *analyses) get(id string) *analysis {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	return as.byID[id]
+}
+
+// remove forgets an analysis
+func (as *analyses) remove(id string) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	delete(as.byID, id)
+}
+
+// stopAll stops every analysis, reporting their best moves so that their streams end
+func (as *analyses) stopAll() {
+	as.mutex.Lock()
+	all := make([]*analysis, 0, len(as.byID))
+	for _, a := range as.byID {
+		all = append(all, a)
+	}
+	as.mutex.Unlock()
+
+	for _, a := range all {
+		a.engine.Stop(false)
+	}
+}
+
+// Handle POST /api/analysis
+func (s *Server) handleStartAnalysis(w http.ResponseWriter, r *http.Request) {
+	request := analysisRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("malformed request: %w", err))
+		return
+	}
+
+	fen := request.FEN
+	if fen == "" {
+		fen = chess.FenStartingPosition
+	}
+
+	board, err := parseFen(fen)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, text := range request.Moves {
+		move, err := board.FindMove(text)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		board.MakeMove(move)
+	}
+
+	if request.Depth < 0 || request.MoveTimeMs < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("depth and moveTimeMs must not be negative"))
+		return
+	}
+
+	limits := engine.Limits{
+		Depth:    request.Depth,
+		MoveTime: time.Duration(request.MoveTimeMs) * time.Millisecond,
+		Infinite: request.Depth == 0 && request.MoveTimeMs == 0,
+	}
+
+	a, err := s.analyses.start(board, limits)
+	if err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+
+	logger.Debug("Started analysis %s of %s", a.id, board.ToFen())
+
+	w.Header().Set("Location", "/api/analysis/"+a.id+"/events")
+	writeJSON(w, http.StatusCreated, analysisResponse{ID: a.id})
+}
+
+// Handle GET /api/analysis/{id}/events
+func (s *Server) handleAnalysisEvents(w http.ResponseWriter, r *http.Request) {
+	a := s.analyses.get(r.PathValue("id"))
+	if a == nil {
+		writeError(w, http.StatusNotFound, errAnalysisNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+
+	sent := 0
+	for {
+		events, changed, finished := a.next(sent)
+
+		for _, e := range events {
+			data, err := json.Marshal(e.data)
+			if err != nil {
+				logger.Error("Error encoding %s event: %s", e.name, err)
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, data)
+		}
+		sent += len(events)
+
+		if err := controller.Flush(); err != nil {
+			return
+		}
+
+		if finished {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Handle DELETE /api/analysis/{id}
+func (s *Server) handleStopAnalysis(w http.ResponseWriter, r *http.Request) {
+	a := s.analyses.get(r.PathValue("id"))
+	if a == nil {
+		writeError(w, http.StatusNotFound, errAnalysisNotFound)
+		return
+	}
+
+	// Stopping reports the best move, which ends any streams
+	a.engine.Stop(false)
+	s.analyses.remove(a.id)
+
+	logger.Debug("Stopped analysis %s", a.id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newScoreResponse converts a search score for JSON
+func newScoreResponse(score utility.Score) scoreResponse {
+	response := scoreResponse{}
+
+	value := score.Value
+	if score.Mate {
+		response.Mate = &value
+	} else {
+		response.Centipawns = &value
+	}
+
+	switch score.Bound {
+	case utility.BoundLower:
+		response.Bound = "lower"
+	case utility.BoundUpper:
+		response.Bound = "upper"
+	}
+
+	return response
+}
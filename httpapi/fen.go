@@ -0,0 +1,113 @@
+package httpapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	// Internal references
+	"goche/chess"
+	"goche/utility"
+)
+
+// The letters of the pieces that may appear in the piece placement of a FEN
+const fenPieces = "PNBRQKpnbrqk"
+
+// parseFen sets up the position given by a FEN. The engine's own parser trusts the GUI to send
+// well-formed FEN, and so reads past mistakes, whereas positions sent to the server are checked
+// strictly first.
+func parseFen(fen string) (*chess.Board, error) {
+	if err := checkFen(fen); err != nil {
+		return nil, fmt.Errorf("invalid FEN: %w", err)
+	}
+
+	board, err := chess.NewBoard(fen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEN: %w", err)
+	}
+
+	return board, nil
+}
+
+// checkFen checks that every field of a FEN is well formed. The clocks may be left out, as they are
+// in EPD, but not just one of them.
+func checkFen(fen string) error {
+	fields := strings.Fields(fen)
+	if len(fields) != 4 && len(fields) != 6 {
+		return fmt.Errorf("expected 4 or 6 fields, found %d", len(fields))
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return fmt.Errorf("expected 8 ranks, found %d", len(ranks))
+	}
+	for i, rank := range ranks {
+		if err := checkRank(rank); err != nil {
+			return fmt.Errorf("rank %d: %w", 8-i, err)
+		}
+	}
+
+	if fields[1] != "w" && fields[1] != "b" {
+		return fmt.Errorf("side to move must be 'w' or 'b', not '%s'", fields[1])
+	}
+
+	if fields[2] != "-" {
+		for i, right := range fields[2] {
+			if !strings.ContainsRune("KQkq", right) || strings.ContainsRune(fields[2][:i], right) {
+				return fmt.Errorf("bad castling rights '%s'", fields[2])
+			}
+		}
+	}
+
+	if fields[3] != "-" {
+		// The square is behind a pawn that has just advanced two squares, so is on the sixth rank
+		// when white is to move and the third when black is
+		square := fields[3]
+		rank := byte(utility.If(fields[1] == "w", '6', '3'))
+		if len(square) != 2 || square[0] < 'a' || square[0] > 'h' || square[1] != rank {
+			return fmt.Errorf("bad en passant square '%s'", square)
+		}
+	}
+
+	if len(fields) == 6 {
+		if halfMoves, err := strconv.Atoi(fields[4]); err != nil || halfMoves < 0 {
+			return fmt.Errorf("halfmove clock must be a non-negative integer, not '%s'", fields[4])
+		}
+		if moveNumber, err := strconv.Atoi(fields[5]); err != nil || moveNumber < 1 {
+			return fmt.Errorf("fullmove number must be a positive integer, not '%s'", fields[5])
+		}
+	}
+
+	return nil
+}
+
+// checkRank checks that one rank of the piece placement covers exactly 8 files
+func checkRank(rank string) error {
+	files := 0
+	previousDigit := false
+
+	for _, character := range rank {
+		switch {
+		case character >= '1' && character <= '8':
+			// Runs of empty squares are written as a single digit
+			if previousDigit {
+				return fmt.Errorf("consecutive digits in '%s'", rank)
+			}
+			files += int(character - '0')
+			previousDigit = true
+
+		case strings.ContainsRune(fenPieces, character):
+			files++
+			previousDigit = false
+
+		default:
+			return fmt.Errorf("unexpected character '%c'", character)
+		}
+	}
+
+	if files != 8 {
+		return fmt.Errorf("expected 8 files, found %d in '%s'", files, rank)
+	}
+
+	return nil
+}
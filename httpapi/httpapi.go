@@ -0,0 +1,298 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+	"goche/logger"
+)
+
+// The deepest perft the server will run, to keep requests from tying up the engine
+const maxPerftDepth = 5
+
+// Perfts shallower than this are counted without checking whether the request has been cancelled
+const perftCancelDepth = 3
+
+// Server exposes the engine over HTTP with JSON bodies, for tools that do not speak UCI:
+//
+//	GET    /api/position?fen=...            validate a position and describe its status
+//	GET    /api/moves?fen=...               list the legal moves in UCI and SAN notation
+//	GET    /api/perft?fen=...&depth=n       count the positions to a depth, optionally divided by move
+//	POST   /api/analysis                    start an analysis, returning its id
+//	GET    /api/analysis/{id}/events        stream the analysis as Server-Sent Events
+//	DELETE /api/analysis/{id}               stop an analysis
+//
+// A missing 'fen' means the starting position.
+type Server struct {
+	analyses *analyses
+	mux      *http.ServeMux
+}
+
+// New creates a server allowing up to the given number of analyses at once
+func New(maxAnalyses int) *Server {
+	s := &Server{
+		analyses: newAnalyses(maxAnalyses),
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /api/position", s.handlePosition)
+	s.mux.HandleFunc("GET /api/moves", s.handleMoves)
+	s.mux.HandleFunc("GET /api/perft", s.handlePerft)
+	s.mux.HandleFunc("POST /api/analysis", s.handleStartAnalysis)
+	s.mux.HandleFunc("GET /api/analysis/{id}/events", s.handleAnalysisEvents)
+	s.mux.HandleFunc("DELETE /api/analysis/{id}", s.handleStopAnalysis)
+
+	return s
+}
+
+// ServeHTTP makes the server an http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger.Debug("HTTP %s %s", r.Method, r.URL)
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops every analysis, ending their event streams
+func (s *Server) Close() {
+	s.analyses.stopAll()
+}
+
+// positionResponse describes a position
+type positionResponse struct {
+	FEN        string `json:"fen"`
+	SideToMove string `json:"sideToMove"`
+	InCheck    bool   `json:"inCheck"`
+	Status     string `json:"status"`
+	Evaluation int    `json:"evaluation"`
+}
+
+// moveResponse is a legal move in both notations
+type moveResponse struct {
+	UCI string `json:"uci"`
+	SAN string `json:"san"`
+}
+
+type movesResponse struct {
+	FEN   string         `json:"fen"`
+	Moves []moveResponse `json:"moves"`
+}
+
+type perftResponse struct {
+	FEN     string         `json:"fen"`
+	Depth   int            `json:"depth"`
+	Nodes   int            `json:"nodes"`
+	Divide  map[string]int `json:"divide,omitempty"`
+	Elapsed int64          `json:"elapsedMs"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handle GET /api/position
+func (s *Server) handlePosition(w http.ResponseWriter, r *http.Request) {
+	board, err := boardFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, positionResponse{
+		FEN:        board.ToFen(),
+		SideToMove: sideToMove(board),
+		InCheck:    board.IsInCheck(),
+		Status:     gameStatus(board),
+		Evaluation: engine.Evaluate(board),
+	})
+}
+
+// Handle GET /api/moves
+func (s *Server) handleMoves(w http.ResponseWriter, r *http.Request) {
+	board, err := boardFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	moveList, err := board.GetMoves(make([]chess.Move, 0, 256))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := movesResponse{
+		FEN:   board.ToFen(),
+		Moves: make([]moveResponse, 0, len(moveList)),
+	}
+	for _, move := range moveList {
+		response.Moves = append(response.Moves, moveResponse{UCI: move.ToUciString(), SAN: board.ToSan(move)})
+	}
+
+	// Sort for a stable listing that is easy to compare
+	sort.Slice(response.Moves, func(i, j int) bool {
+		return response.Moves[i].UCI < response.Moves[j].UCI
+	})
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Handle GET /api/perft
+func (s *Server) handlePerft(w http.ResponseWriter, r *http.Request) {
+	board, err := boardFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	depth, err := strconv.Atoi(r.URL.Query().Get("depth"))
+	if err != nil || depth < 1 || depth > maxPerftDepth {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("depth must be between 1 and %d", maxPerftDepth))
+		return
+	}
+
+	// A perft takes up a CPU as an analysis does, so it counts against the same limit
+	done, err := s.analyses.startPerft()
+	if err != nil {
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	defer done()
+
+	response := perftResponse{
+		FEN:   board.ToFen(),
+		Depth: depth,
+	}
+
+	start := time.Now()
+
+	if r.URL.Query().Get("divide") == "true" {
+		response.Divide, err = perftDivide(r.Context(), board, depth)
+		for _, nodes := range response.Divide {
+			response.Nodes += nodes
+		}
+	} else {
+		response.Nodes, err = perft(r.Context(), board, depth)
+	}
+
+	if r.Context().Err() != nil {
+		logger.Debug("Perft of %s to depth %d cancelled", response.FEN, depth)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.Elapsed = time.Since(start).Milliseconds()
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// perft counts the positions to the depth as chess.Perft does, giving up if the context is cancelled
+func perft(ctx context.Context, board *chess.Board, depth int) (int, error) {
+	if depth < perftCancelDepth {
+		return chess.Perft(board, depth)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	moveList, err := board.GetMoves(make([]chess.Move, 0, 256))
+	if err != nil {
+		return 0, fmt.Errorf("move generation failed: %w", err)
+	}
+
+	nodes := 0
+	for _, move := range moveList {
+		undo := board.MakeMove(move)
+		moveNodes, err := perft(ctx, board, depth-1)
+		board.UnmakeMove(undo)
+
+		if err != nil {
+			return 0, err
+		}
+		nodes += moveNodes
+	}
+
+	return nodes, nil
+}
+
+// perftDivide counts the positions to the depth after each legal move as chess.PerftDivide does,
+// giving up if the context is cancelled
+func perftDivide(ctx context.Context, board *chess.Board, depth int) (map[string]int, error) {
+	moveList, err := board.GetMoves(make([]chess.Move, 0, 256))
+	if err != nil {
+		return nil, fmt.Errorf("move generation failed: %w", err)
+	}
+
+	divided := make(map[string]int, len(moveList))
+	for _, move := range moveList {
+		undo := board.MakeMove(move)
+		nodes, err := perft(ctx, board, depth-1)
+		board.UnmakeMove(undo)
+
+		if err != nil {
+			return nil, err
+		}
+		divided[move.ToUciString()] = nodes
+	}
+
+	return divided, nil
+}
+
+// boardFromRequest sets up the position given by the 'fen' query parameter
+func boardFromRequest(r *http.Request) (*chess.Board, error) {
+	fen := strings.TrimSpace(r.URL.Query().Get("fen"))
+	if fen == "" {
+		fen = chess.FenStartingPosition
+	}
+
+	return parseFen(fen)
+}
+
+// gameStatus describes whether the game is over in the position
+func gameStatus(board *chess.Board) string {
+	switch board.Status() {
+	case chess.Checkmate:
+		return "checkmate"
+	case chess.Stalemate:
+		return "stalemate"
+	case chess.FiftyMoveRule:
+		return "fiftyMoveRule"
+	case chess.InsufficientMaterial:
+		return "insufficientMaterial"
+	}
+
+	return "ongoing"
+}
+
+// sideToMove names the side to move
+func sideToMove(board *chess.Board) string {
+	if board.IsWhiteToMove() {
+		return "white"
+	}
+	return "black"
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Error writing HTTP response: %s", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,269 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+)
+
+func get(t *testing.T, s *Server, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestPositionRejectsMalformedFen(t *testing.T) {
+	s := New(1)
+
+	malformed := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNRR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -5 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0",
+		"rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/44/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e3 0 1",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e6 0 2",
+	}
+
+	for _, fen := range malformed {
+		if response := get(t, s, "/api/position?fen="+url.QueryEscape(fen)); response.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", fen, response.Code, http.StatusBadRequest)
+		}
+	}
+
+	wellFormed := []string{
+		chess.FenStartingPosition,
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+	}
+
+	for _, fen := range wellFormed {
+		if response := get(t, s, "/api/position?fen="+url.QueryEscape(fen)); response.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want %d", fen, response.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestPerftCountsAgainstAnalyses(t *testing.T) {
+	s := New(1)
+
+	if response := get(t, s, "/api/perft?depth=3"); response.Code != http.StatusOK {
+		t.Fatalf("Perft status %d, want %d", response.Code, http.StatusOK)
+	}
+
+	if response := get(t, s, "/api/perft?depth=6"); response.Code != http.StatusBadRequest {
+		t.Errorf("Perft beyond the maximum depth status %d, want %d", response.Code, http.StatusBadRequest)
+	}
+
+	// While the only place is taken by a perft, an analysis is refused
+	done, err := s.analyses.startPerft()
+	if err != nil {
+		t.Fatalf("startPerft: %s", err)
+	}
+	board, _ := chess.NewBoard(chess.FenStartingPosition)
+	if _, err := s.analyses.start(board, engine.Limits{Depth: 1}); err == nil {
+		t.Errorf("Analysis started while a perft was running")
+	}
+	if response := get(t, s, "/api/perft?depth=1"); response.Code != http.StatusTooManyRequests {
+		t.Errorf("Second perft status %d, want %d", response.Code, http.StatusTooManyRequests)
+	}
+	done()
+
+	if response := get(t, s, "/api/perft?depth=1"); response.Code != http.StatusOK {
+		t.Errorf("Perft after the place was freed status %d, want %d", response.Code, http.StatusOK)
+	}
+}
+
+func TestPerftCancelled(t *testing.T) {
+	s := New(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "/api/perft?depth=5&divide=true", nil).WithContext(ctx)
+
+	served := make(chan struct{})
+	go func() {
+		s.ServeHTTP(httptest.NewRecorder(), request)
+		close(served)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-served:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Perft still running after its request was cancelled")
+	}
+
+	// The place it took is given back
+	if response := get(t, s, "/api/perft?depth=1"); response.Code != http.StatusOK {
+		t.Errorf("Perft after cancellation status %d, want %d", response.Code, http.StatusOK)
+	}
+}
+
+// serverEvent is one Server-Sent Event read from a stream
+type serverEvent struct {
+	name string
+	data string
+}
+
+// readEvents reads the events of a stream onto a channel, which is closed when the stream ends
+func readEvents(body io.Reader) <-chan serverEvent {
+	events := make(chan serverEvent)
+
+	go func() {
+		defer close(events)
+
+		e := serverEvent{}
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				e.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				events <- e
+				e = serverEvent{}
+			}
+		}
+	}()
+
+	return events
+}
+
+// nextEvent returns the next event of a stream, failing if it ends or nothing arrives within the timeout
+func nextEvent(t *testing.T, events <-chan serverEvent, timeout time.Duration) serverEvent {
+	t.Helper()
+
+	select {
+	case e, open := <-events:
+		if !open {
+			t.Fatal("Event stream ended")
+		}
+		return e
+	case <-time.After(timeout):
+		t.Fatalf("No event within %s", timeout)
+	}
+	return serverEvent{}
+}
+
+func TestAnalysisStreamsUntilStopped(t *testing.T) {
+	s := New(1)
+	server := httptest.NewServer(s)
+	defer server.Close()
+	defer s.Close()
+
+	// Without a depth or move time the analysis runs until it is stopped
+	response, err := http.Post(server.URL+"/api/analysis", "application/json",
+		strings.NewReader(`{"fen":"`+chess.FenStartingPosition+`","moves":["e2e4"]}`))
+	if err != nil {
+		t.Fatalf("Starting analysis: %s", err)
+	}
+	started := analysisResponse{}
+	json.NewDecoder(response.Body).Decode(&started)
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated || started.ID == "" {
+		t.Fatalf("Starting analysis status %d, id '%s'", response.StatusCode, started.ID)
+	}
+
+	stream, err := http.Get(server.URL + response.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Opening event stream: %s", err)
+	}
+	defer stream.Body.Close()
+	if contentType := stream.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Event stream content type '%s'", contentType)
+	}
+	events := readEvents(stream.Body)
+
+	// Each info event gives a score and a line of play for black, after 1. e4
+	board, _ := chess.NewBoard(chess.FenStartingPosition)
+	move, _ := board.FindMove("e2e4")
+	board.MakeMove(move)
+
+	depth := 0
+	for depth < 3 {
+		e := nextEvent(t, events, 5*time.Second)
+		if e.name != "info" {
+			t.Fatalf("Event '%s' before depth 3, want 'info'", e.name)
+		}
+
+		info := infoEvent{}
+		if err := json.Unmarshal([]byte(e.data), &info); err != nil {
+			t.Fatalf("Malformed info event %s: %s", e.data, err)
+		}
+		if info.Depth < depth {
+			t.Errorf("Depth went from %d to %d", depth, info.Depth)
+		}
+		if (info.Score.Centipawns == nil) == (info.Score.Mate == nil) {
+			t.Errorf("Info event %s without exactly one of cp and mate", e.data)
+		}
+		if len(info.PV) == 0 {
+			t.Errorf("Info event %s without a principal variation", e.data)
+		} else if _, err := board.FindMove(info.PV[0]); err != nil {
+			t.Errorf("Info event %s starts with an illegal move", e.data)
+		}
+		depth = info.Depth
+	}
+
+	// Stopping the analysis reports the best move, which ends the stream
+	request, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/analysis/"+started.ID, nil)
+	stopped, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Stopping analysis: %s", err)
+	}
+	stopped.Body.Close()
+	if stopped.StatusCode != http.StatusNoContent {
+		t.Errorf("Stopping analysis status %d, want %d", stopped.StatusCode, http.StatusNoContent)
+	}
+
+	e := nextEvent(t, events, 5*time.Second)
+	for e.name == "info" {
+		e = nextEvent(t, events, 5*time.Second)
+	}
+	bestMove := bestMoveEvent{}
+	if err := json.Unmarshal([]byte(e.data), &bestMove); e.name != "bestmove" || err != nil {
+		t.Fatalf("Event '%s' %s after stopping, want 'bestmove'", e.name, e.data)
+	}
+	if _, err := board.FindMove(bestMove.BestMove); err != nil {
+		t.Errorf("Best move '%s' is illegal", bestMove.BestMove)
+	}
+
+	select {
+	case e, open := <-events:
+		if open {
+			t.Errorf("Event '%s' after the best move", e.name)
+		}
+	case <-time.After(time.Second):
+		t.Error("Event stream still open after the best move")
+	}
+
+	// The stopped analysis is gone, and its place free for another
+	if response := get(t, s, "/api/analysis/"+started.ID+"/events"); response.Code != http.StatusNotFound {
+		t.Errorf("Events of a stopped analysis status %d, want %d", response.Code, http.StatusNotFound)
+	}
+	if s.analyses.running() != 0 {
+		t.Errorf("%d analyses running after stopping the only one", s.analyses.running())
+	}
+}
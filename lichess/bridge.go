@@ -0,0 +1,412 @@
+package lichess
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+	"goche/logger"
+	"goche/utility"
+)
+
+// Bridge plays games on lichess with the engine, accepting challenges according to its policy
+type Bridge struct {
+	client  *Client
+	policy  Policy
+	account Account
+
+	mutex sync.Mutex
+	games map[string]context.CancelFunc
+	wait  sync.WaitGroup
+
+	// The challenges we have accepted whose games have not started yet. A game has the id of the
+	// challenge it comes from.
+	accepted map[string]bool
+}
+
+// NewBridge creates a bridge between the lichess account used by the client and the engine
+func NewBridge(client *Client, policy Policy) *Bridge {
+	return &Bridge{
+		client:   client,
+		policy:   policy,
+		games:    make(map[string]context.CancelFunc),
+		accepted: make(map[string]bool),
+	}
+}
+
+// Run handles the account's events until the event stream ends or the context is cancelled, then
+// waits for the games in progress to finish
+func (b *Bridge) Run(ctx context.Context) error {
+	account, err := b.client.Account(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read the account: %w", err)
+	}
+	b.account = account
+
+	logger.Warn("Connected to lichess as %s", account.Username)
+
+	err = b.client.StreamEvents(ctx, func(event Event) {
+		b.handleEvent(ctx, event)
+	})
+
+	b.wait.Wait()
+
+	return err
+}
+
+// handleEvent responds to a line of the event stream
+func (b *Bridge) handleEvent(ctx context.Context, event Event) {
+	switch event.Type {
+	case "challenge":
+		if event.Challenge != nil {
+			b.handleChallenge(ctx, *event.Challenge)
+		}
+
+	case "gameStart":
+		if event.Game != nil {
+			b.startGame(ctx, gameID(*event.Game))
+		}
+
+	case "gameFinish":
+		if event.Game != nil {
+			logger.Debug("Game %s finished", gameID(*event.Game))
+		}
+
+	case "challengeCanceled", "challengeDeclined":
+		if event.Challenge != nil {
+			logger.Debug("Challenge %s %s", event.Challenge.ID, strings.TrimPrefix(event.Type, "challenge"))
+			b.forgetChallenge(event.Challenge.ID)
+		}
+
+	default:
+		logger.Debug("Ignored lichess event '%s'", event.Type)
+	}
+}
+
+// handleChallenge accepts or declines a challenge according to the policy
+func (b *Bridge) handleChallenge(ctx context.Context, challenge Challenge) {
+	// Our own challenges to others appear in the stream too
+	if strings.EqualFold(challenge.Challenger.ID, b.account.ID) {
+		return
+	}
+
+	reason := b.policy.Evaluate(challenge, b.gamesInProgress())
+	if reason != "" {
+		logger.Warn("Declining challenge %s from %s: %s", challenge.ID, challenge.Challenger.Name, reason)
+		if err := b.client.DeclineChallenge(ctx, challenge.ID, reason); err != nil {
+			logger.Error("Unable to decline challenge %s: %s", challenge.ID, err)
+		}
+		return
+	}
+
+	// The game counts against the limit from now, rather than only once it starts
+	b.mutex.Lock()
+	b.accepted[challenge.ID] = true
+	b.mutex.Unlock()
+
+	logger.Warn("Accepting challenge %s from %s", challenge.ID, challenge.Challenger.Name)
+	if err := b.client.AcceptChallenge(ctx, challenge.ID); err != nil {
+		logger.Error("Unable to accept challenge %s: %s", challenge.ID, err)
+		b.forgetChallenge(challenge.ID)
+	}
+}
+
+// forgetChallenge stops counting a challenge we accepted, as its game will not start
+func (b *Bridge) forgetChallenge(id string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.accepted, id)
+}
+
+// startGame plays a game in its own goroutine, unless it is already being played
+func (b *Bridge) startGame(ctx context.Context, id string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.accepted, id)
+	if _, playing := b.games[id]; playing {
+		return
+	}
+
+	gameCtx, cancel := context.WithCancel(ctx)
+	b.games[id] = cancel
+	b.wait.Add(1)
+
+	go func() {
+		defer func() {
+			b.mutex.Lock()
+			delete(b.games, id)
+			b.mutex.Unlock()
+
+			cancel()
+			b.wait.Done()
+		}()
+
+		g := &game{bridge: b, id: id, engine: engine.New(), lastMoveCount: -1, lastDrawCount: -1, lastTakebackCount: -1}
+		g.play(gameCtx)
+	}()
+}
+
+// gamesInProgress returns the number of games being played, or about to be as we have accepted them
+func (b *Bridge) gamesInProgress() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.games) + len(b.accepted)
+}
+
+// game is the state of a single game being played
+type game struct {
+	bridge     *Bridge
+	id         string
+	engine     *engine.Engine
+	white      bool
+	initialFen string
+
+	// The number of moves in the latest state
+	moveCount int
+
+	// The number of moves in the game when we last moved, or responded to an offer, so that we
+	// respond once to each
+	lastMoveCount     int
+	lastDrawCount     int
+	lastTakebackCount int
+
+	// The search for our move, if there is one, which runs while the game stream is read
+	thinking *thinking
+}
+
+// thinking is a search for our move in the position after a number of moves
+type thinking struct {
+	moveCount int
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// play reads the game stream, moving whenever it is our turn, until the game ends. The stream is read
+// in a goroutine of its own, so that the game's state is followed while we think.
+func (g *game) play(ctx context.Context) {
+	logger.Warn("Playing game %s", g.id)
+
+	events := make(chan GameEvent, 16)
+	streamed := make(chan error, 1)
+	go func() {
+		defer close(events)
+
+		streamed <- g.bridge.client.StreamGame(ctx, g.id, func(event GameEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	for event := range events {
+		switch event.Type {
+		case "gameFull":
+			g.white = strings.EqualFold(event.White.ID, g.bridge.account.ID)
+			g.initialFen = event.InitialFen
+			if event.State != nil {
+				g.handleState(ctx, *event.State)
+			}
+
+		case "gameState":
+			g.handleState(ctx, event.GameState)
+
+		case "opponentGone":
+			logger.Debug("Game %s: opponent gone: %t", g.id, event.Gone)
+
+		case "chatLine":
+			logger.Debug("Game %s: %s says '%s'", g.id, event.Username, event.Text)
+		}
+	}
+
+	// Once the stream has ended there is no game left to move in
+	g.stopThinking()
+
+	if err := <-streamed; err != nil {
+		logger.Error("Game %s stream ended: %s", g.id, err)
+	}
+
+	logger.Warn("Finished game %s", g.id)
+}
+
+// handleState responds to the latest state of the game
+func (g *game) handleState(ctx context.Context, state GameState) {
+	if state.Status != "" && state.Status != "started" && state.Status != "created" {
+		logger.Warn("Game %s ended: %s %s", g.id, state.Status, state.Winner)
+		g.stopThinking()
+		return
+	}
+
+	board, moveCount, err := g.position(state.Moves)
+	if err != nil {
+		// We cannot play on from a position we do not understand
+		logger.Error("Game %s: %s", g.id, err)
+		if err := g.bridge.client.Abort(ctx, g.id); err != nil {
+			logger.Error("Unable to abort game %s: %s", g.id, err)
+		}
+		return
+	}
+
+	// A search of a position that has since changed, as after a takeback, would find a move that
+	// can no longer be played
+	if g.thinking != nil && g.thinking.moveCount != moveCount {
+		g.stopThinking()
+	}
+
+	// After a takeback we may need to move again in a position we have already moved in
+	if moveCount < g.moveCount {
+		g.lastMoveCount = -1
+	}
+	g.moveCount = moveCount
+
+	ourTurn := board.IsWhiteToMove() == g.white
+
+	// Offers are made by the opponent, and answered once each
+	opponentDraw := utility.If(g.white, state.BlackDraw, state.WhiteDraw)
+	if opponentDraw && g.lastDrawCount != moveCount {
+		g.lastDrawCount = moveCount
+
+		// Accept only when we are not winning, by the engine's own evaluation
+		evaluation := engine.Evaluate(board)
+		if !ourTurn {
+			evaluation = -evaluation
+		}
+		accept := g.bridge.policy.AcceptDraws && evaluation <= 0
+
+		logger.Warn("Game %s: %s draw offer", g.id, utility.If(accept, "accepting", "declining"))
+		if err := g.bridge.client.HandleDrawOffer(ctx, g.id, accept); err != nil {
+			logger.Error("Unable to answer draw offer in game %s: %s", g.id, err)
+		}
+		if accept {
+			return
+		}
+	}
+
+	opponentTakeback := utility.If(g.white, state.BlackBack, state.WhiteBack)
+	if opponentTakeback && g.lastTakebackCount != moveCount {
+		g.lastTakebackCount = moveCount
+
+		accept := g.bridge.policy.AcceptTakebacks
+
+		logger.Warn("Game %s: %s takeback", g.id, utility.If(accept, "accepting", "declining"))
+		if err := g.bridge.client.HandleTakeback(ctx, g.id, accept); err != nil {
+			logger.Error("Unable to answer takeback in game %s: %s", g.id, err)
+		}
+		if accept {
+			return
+		}
+	}
+
+	if !ourTurn || g.lastMoveCount == moveCount {
+		return
+	}
+	g.lastMoveCount = moveCount
+
+	g.startThinking(ctx, board, state, moveCount)
+}
+
+// startThinking searches for our move in the background, and plays it unless the search is stopped
+func (g *game) startThinking(ctx context.Context, board *chess.Board, state GameState, moveCount int) {
+	g.stopThinking()
+
+	searchCtx, cancel := context.WithCancel(ctx)
+	t := &thinking{moveCount: moveCount, cancel: cancel, done: make(chan struct{})}
+	g.thinking = t
+
+	go func() {
+		defer close(t.done)
+
+		move, err := g.think(searchCtx, board, state)
+		if searchCtx.Err() != nil {
+			logger.Debug("Game %s: search stopped", g.id)
+			return
+		}
+		if err != nil {
+			logger.Error("Game %s: %s", g.id, err)
+			return
+		}
+
+		if err := g.bridge.client.MakeMove(searchCtx, g.id, move); err != nil {
+			logger.Error("Unable to play %s in game %s: %s", move, g.id, err)
+		}
+	}()
+}
+
+// stopThinking abandons the search for our move, if there is one, and waits for it to finish
+func (g *game) stopThinking() {
+	if g.thinking == nil {
+		return
+	}
+
+	g.thinking.cancel()
+	<-g.thinking.done
+	g.thinking = nil
+}
+
+// position sets up the board from the game's starting position and its moves so far
+func (g *game) position(moves string) (*chess.Board, int, error) {
+	fen := g.initialFen
+	if fen == "" || fen == "startpos" {
+		fen = chess.FenStartingPosition
+	}
+
+	board, err := chess.NewBoard(fen)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid starting position: %w", err)
+	}
+
+	moveList := strings.Fields(moves)
+	for _, text := range moveList {
+		move, err := board.FindMove(text)
+		if err != nil {
+			return nil, 0, err
+		}
+		board.MakeMove(move)
+	}
+
+	return board, len(moveList), nil
+}
+
+// think searches for our move with the time left on our clock, until it finds one or the context is
+// cancelled
+func (g *game) think(ctx context.Context, board *chess.Board, state GameState) (string, error) {
+	limits := engine.Limits{}
+	limits.SetClock(true, clockDuration(state.WhiteTime), clockDuration(state.WhiteInc))
+	limits.SetClock(false, clockDuration(state.BlackTime), clockDuration(state.BlackInc))
+
+	reporter := engine.NewChannelReporter(16)
+	g.engine.SetPosition(board)
+	g.engine.Go(limits, reporter)
+
+	for {
+		select {
+		case progress := <-reporter.Progress:
+			logger.Debug("Game %s: depth %d score %s pv %s", g.id, progress.Depth, progress.Score, strings.Join(progress.PV, " "))
+
+		case result := <-reporter.Result:
+			if result.BestMove == chess.NullMoveString {
+				return "", fmt.Errorf("no move found")
+			}
+			return result.BestMove, nil
+
+		case <-ctx.Done():
+			g.engine.Stop(true)
+			return "", ctx.Err()
+		}
+	}
+}
+
+// gameID returns the id of a game that has started
+func gameID(start GameStart) string {
+	if start.GameID != "" {
+		return start.GameID
+	}
+	return start.ID
+}
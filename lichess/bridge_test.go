@@ -0,0 +1,274 @@
+package lichess
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	// Internal references
+	"goche/engine"
+)
+
+// The recorded streams and policy that the bridge is tested against
+const fixtures = "../test/lichess"
+
+// The bot's clock in the recorded games is cut to this, in milliseconds, so that it searches briefly
+const testClock = 2000
+
+// stub stands in for lichess. It replays the recorded event stream, feeds game streams a line at a
+// time from the test, and records what the bridge posts.
+type stub struct {
+	t      *testing.T
+	server *httptest.Server
+	games  map[string]chan string
+
+	mutex sync.Mutex
+	posts []string
+}
+
+func newStub(t *testing.T, games ...string) *stub {
+	s := &stub{t: t, games: make(map[string]chan string)}
+	for _, id := range games {
+		s.games[id] = make(chan string)
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/account", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"id":"goche","username":"goche"}`)
+	})
+
+	mux.HandleFunc("GET /api/stream/event", func(w http.ResponseWriter, r *http.Request) {
+		for _, line := range readLines(t, filepath.Join(fixtures, "events.ndjson")) {
+			fmt.Fprintln(w, line)
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	mux.HandleFunc("GET /api/bot/game/stream/{id}", func(w http.ResponseWriter, r *http.Request) {
+		lines, found := s.games[r.PathValue("id")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		for {
+			select {
+			case line, open := <-lines:
+				if !open {
+					return
+				}
+				fmt.Fprintln(w, line)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	mux.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		post := r.URL.Path
+		if reason := r.PostForm.Get("reason"); reason != "" {
+			post += " " + reason
+		}
+
+		s.mutex.Lock()
+		s.posts = append(s.posts, post)
+		s.mutex.Unlock()
+
+		fmt.Fprintln(w, `{"ok":true}`)
+	})
+
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// client returns a client of the stub
+func (s *stub) client() *Client {
+	return &Client{BaseURL: s.server.URL, Token: "test", API: BotAPI, HTTPClient: s.server.Client()}
+}
+
+// count returns the number of posts starting with the prefix
+func (s *stub) count(prefix string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+	for _, post := range s.posts {
+		if strings.HasPrefix(post, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// waitFor fails unless the bridge has made the given number of posts starting with the prefix
+// within the timeout
+func (s *stub) waitFor(prefix string, count int, timeout time.Duration) {
+	s.t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for s.count(prefix) < count {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("Posted '%s' %d times, want %d, posts %q", prefix, s.count(prefix), count, s.posts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readLines reads a recorded NDJSON stream
+func readLines(t *testing.T, filename string) []string {
+	t.Helper()
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Opening %s: %s", filename, err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// shortenClock cuts the clock of black, which the bot plays, in a game stream line
+func shortenClock(t *testing.T, line string) string {
+	if strings.TrimSpace(line) == "" {
+		return line
+	}
+
+	event := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		t.Fatalf("Malformed recorded line %s: %s", line, err)
+	}
+
+	state := event
+	if nested, ok := event["state"].(map[string]interface{}); ok {
+		state = nested
+	}
+	if _, ok := state["btime"]; ok {
+		state["btime"] = testClock
+	}
+
+	data, _ := json.Marshal(event)
+	return string(data)
+}
+
+func TestBridgeReplaysRecordedGame(t *testing.T) {
+	s := newStub(t, "game0001")
+
+	policy, err := LoadPolicy(filepath.Join(fixtures, "policy.json"))
+	if err != nil {
+		t.Fatalf("LoadPolicy: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := make(chan error, 1)
+	go func() {
+		ran <- NewBridge(s.client(), policy).Run(ctx)
+	}()
+
+	// Each challenge is answered according to the policy. Erin's arrives while the game accepted from
+	// Alice has yet to start, and Dave's once it has, and either would exceed the one game allowed.
+	for _, post := range []string{
+		"/api/challenge/game0001/accept",
+		"/api/challenge/chal0002/decline variant",
+		"/api/challenge/chal0003/decline noBot",
+		"/api/challenge/chal0004/decline timeControl",
+		"/api/challenge/chal0006/decline later",
+		"/api/challenge/chal0005/decline later",
+	} {
+		s.waitFor(post, 1, 5*time.Second)
+	}
+
+	// The game is fed a line at a time, waiting for the bridge's response to those that need one
+	responses := map[int]struct {
+		prefix string
+		count  int
+	}{
+		2: {"/api/bot/game/game0001/move/", 1},
+		5: {"/api/bot/game/game0001/move/", 2},
+		6: {"/api/bot/game/game0001/takeback/no", 1},
+		9: {"/api/bot/game/game0001/draw/yes", 1},
+	}
+
+	lines := s.games["game0001"]
+	for i, line := range readLines(t, filepath.Join(fixtures, "game0001.ndjson")) {
+		lines <- shortenClock(t, line)
+
+		if response, found := responses[i+1]; found {
+			s.waitFor(response.prefix, response.count, 10*time.Second)
+		}
+	}
+	close(lines)
+
+	select {
+	case err := <-ran:
+		if err != nil {
+			t.Errorf("Run: %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Bridge still running after the streams ended")
+	}
+
+	// Once each, and no more
+	for prefix, want := range map[string]int{
+		"/api/challenge/":                     6,
+		"/api/challenge/game0001/accept":      1,
+		"/api/bot/game/game0001/move/":        2,
+		"/api/bot/game/game0001/takeback/no":  1,
+		"/api/bot/game/game0001/takeback/yes": 0,
+		"/api/bot/game/game0001/draw/yes":     1,
+		"/api/bot/game/game0001/draw/no":      0,
+		"/api/bot/game/game0001/abort":        0,
+	} {
+		if count := s.count(prefix); count != want {
+			t.Errorf("Posted '%s' %d times, want %d", prefix, count, want)
+		}
+	}
+}
+
+func TestTakebackStopsSearch(t *testing.T) {
+	s := newStub(t)
+	ctx := context.Background()
+
+	b := NewBridge(s.client(), DefaultPolicy())
+	b.account = Account{ID: "goche", Username: "goche"}
+	g := &game{bridge: b, id: "game0002", engine: engine.New(), lastMoveCount: -1, lastDrawCount: -1, lastTakebackCount: -1}
+
+	// With five minutes on the clock, the search for a reply to e2e4 takes seconds
+	g.handleState(ctx, GameState{Moves: "e2e4", WhiteTime: 300000, BlackTime: 300000, Status: "started"})
+	time.Sleep(100 * time.Millisecond)
+
+	if g.thinking == nil || !g.engine.Searching() {
+		t.Fatal("Not searching for a reply to e2e4")
+	}
+
+	// The takeback leaves white to move again, so the move being searched for can no longer be played
+	g.handleState(ctx, GameState{Moves: "", WhiteTime: 300000, BlackTime: 300000, Status: "started"})
+
+	if g.thinking != nil || g.engine.Searching() {
+		t.Error("Still searching after the takeback")
+	}
+	if count := s.count("/api/bot/game/game0002/move/"); count != 0 {
+		t.Errorf("Posted %d moves after the takeback", count)
+	}
+}
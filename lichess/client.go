@@ -0,0 +1,302 @@
+package lichess
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	// Internal references
+	"goche/logger"
+)
+
+// DefaultBaseURL is the address of lichess itself
+const DefaultBaseURL = "https://lichess.org"
+
+// The largest line expected in an NDJSON stream, which is a 'gameFull' event with a long move list
+const maxStreamLine = 1024 * 1024
+
+// API selects the set of lichess endpoints used to play: the Bot API for bot accounts, or the
+// Board API for ordinary accounts
+type API string
+
+const (
+	BotAPI   API = "bot"
+	BoardAPI API = "board"
+)
+
+// Client calls the lichess HTTP API. The base URL and HTTP client can be replaced, for example to
+// run against a local stub server that replays recorded streams.
+type Client struct {
+	BaseURL    string
+	Token      string
+	API        API
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client for lichess itself
+func NewClient(token string, api API) *Client {
+	return &Client{
+		BaseURL: DefaultBaseURL,
+		Token:   token,
+		API:     api,
+
+		// No overall timeout, as the event and game streams stay open indefinitely
+		HTTPClient: &http.Client{},
+	}
+}
+
+// Account is the lichess account the client acts for
+type Account struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Player is one side of a challenge or game
+type Player struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Title  string `json:"title"`
+	Rating int    `json:"rating"`
+}
+
+// TimeControl describes a challenge's clock, with times in seconds
+type TimeControl struct {
+	Type      string `json:"type"`
+	Limit     int    `json:"limit"`
+	Increment int    `json:"increment"`
+}
+
+// Variant names a chess variant
+type Variant struct {
+	Key string `json:"key"`
+}
+
+// Challenge is an offer of a game
+type Challenge struct {
+	ID          string      `json:"id"`
+	Challenger  Player      `json:"challenger"`
+	DestUser    Player      `json:"destUser"`
+	Variant     Variant     `json:"variant"`
+	Rated       bool        `json:"rated"`
+	Speed       string      `json:"speed"`
+	TimeControl TimeControl `json:"timeControl"`
+	Color       string      `json:"color"`
+}
+
+// GameStart identifies a game that has started
+type GameStart struct {
+	ID     string `json:"id"`
+	GameID string `json:"gameId"`
+}
+
+// Event is a line of the account's event stream
+type Event struct {
+	Type      string     `json:"type"`
+	Challenge *Challenge `json:"challenge"`
+	Game      *GameStart `json:"game"`
+}
+
+// GameState is the moves and clocks of a game, with times in milliseconds
+type GameState struct {
+	Type      string `json:"type"`
+	Moves     string `json:"moves"`
+	WhiteTime int64  `json:"wtime"`
+	BlackTime int64  `json:"btime"`
+	WhiteInc  int64  `json:"winc"`
+	BlackInc  int64  `json:"binc"`
+	Status    string `json:"status"`
+	Winner    string `json:"winner"`
+	WhiteDraw bool   `json:"wdraw"`
+	BlackDraw bool   `json:"bdraw"`
+	WhiteBack bool   `json:"wtakeback"`
+	BlackBack bool   `json:"btakeback"`
+}
+
+// GameEvent is a line of a game stream: 'gameFull' first, then 'gameState', 'chatLine' and 'opponentGone'
+type GameEvent struct {
+	GameState
+
+	// Set for 'gameFull'
+	ID         string     `json:"id"`
+	Variant    Variant    `json:"variant"`
+	White      Player     `json:"white"`
+	Black      Player     `json:"black"`
+	InitialFen string     `json:"initialFen"`
+	State      *GameState `json:"state"`
+
+	// Set for 'chatLine'
+	Username string `json:"username"`
+	Text     string `json:"text"`
+
+	// Set for 'opponentGone'
+	Gone bool `json:"gone"`
+}
+
+// Account returns the account the token belongs to
+func (c *Client) Account(ctx context.Context) (Account, error) {
+	account := Account{}
+
+	response, err := c.do(ctx, http.MethodGet, "/api/account", nil)
+	if err != nil {
+		return account, err
+	}
+	defer response.Body.Close()
+
+	if err := json.NewDecoder(response.Body).Decode(&account); err != nil {
+		return account, fmt.Errorf("malformed account: %w", err)
+	}
+
+	return account, nil
+}
+
+// StreamEvents reads the account's event stream, calling the handler for each event, until the
+// stream ends or the context is cancelled
+func (c *Client) StreamEvents(ctx context.Context, handler func(Event)) error {
+	return c.stream(ctx, "/api/stream/event", func(line []byte) error {
+		event := Event{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		handler(event)
+		return nil
+	})
+}
+
+// StreamGame reads a game's stream, calling the handler for each event, until the stream ends or
+// the context is cancelled
+func (c *Client) StreamGame(ctx context.Context, gameID string, handler func(GameEvent)) error {
+	return c.stream(ctx, fmt.Sprintf("/api/%s/game/stream/%s", c.API, gameID), func(line []byte) error {
+		event := GameEvent{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		handler(event)
+		return nil
+	})
+}
+
+// AcceptChallenge accepts a challenge
+func (c *Client) AcceptChallenge(ctx context.Context, challengeID string) error {
+	return c.post(ctx, fmt.Sprintf("/api/challenge/%s/accept", challengeID), nil)
+}
+
+// DeclineChallenge declines a challenge, giving one of the reasons lichess recognises, e.g. 'variant'
+func (c *Client) DeclineChallenge(ctx context.Context, challengeID string, reason string) error {
+	return c.post(ctx, fmt.Sprintf("/api/challenge/%s/decline", challengeID), url.Values{"reason": {reason}})
+}
+
+// MakeMove plays a move in UCI notation
+func (c *Client) MakeMove(ctx context.Context, gameID string, move string) error {
+	return c.post(ctx, fmt.Sprintf("/api/%s/game/%s/move/%s", c.API, gameID, move), nil)
+}
+
+// HandleDrawOffer accepts or declines the opponent's draw offer
+func (c *Client) HandleDrawOffer(ctx context.Context, gameID string, accept bool) error {
+	return c.post(ctx, fmt.Sprintf("/api/%s/game/%s/draw/%s", c.API, gameID, yesNo(accept)), nil)
+}
+
+// HandleTakeback accepts or declines the opponent's takeback proposal
+func (c *Client) HandleTakeback(ctx context.Context, gameID string, accept bool) error {
+	return c.post(ctx, fmt.Sprintf("/api/%s/game/%s/takeback/%s", c.API, gameID, yesNo(accept)), nil)
+}
+
+// Abort aborts a game, which is only possible before each side has moved
+func (c *Client) Abort(ctx context.Context, gameID string) error {
+	return c.post(ctx, fmt.Sprintf("/api/%s/game/%s/abort", c.API, gameID), nil)
+}
+
+// post makes a request whose response is only checked for success
+func (c *Client) post(ctx context.Context, path string, form url.Values) error {
+	response, err := c.do(ctx, http.MethodPost, path, form)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	return nil
+}
+
+// stream reads an NDJSON response line by line. Empty lines are keep-alives and are skipped.
+func (c *Client) stream(ctx context.Context, path string, handler func(line []byte) error) error {
+	response, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		logger.Debug("Received from %s: %s", path, line)
+
+		if err := handler(line); err != nil {
+			logger.Error("Skipped malformed line from %s: %s", path, err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+// do sends an authorised request, returning an error for an unsuccessful status
+func (c *Client) do(ctx context.Context, method string, path string, form url.Values) (*http.Response, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if form != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	logger.Debug("%s %s", method, path)
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close()
+		return nil, fmt.Errorf("%s %s failed with %s: %s", method, path, response.Status, strings.TrimSpace(string(message)))
+	}
+
+	return response, nil
+}
+
+// yesNo formats a decision as lichess expects it in a path
+func yesNo(accept bool) string {
+	if accept {
+		return "yes"
+	}
+	return "no"
+}
+
+// clockDuration converts a lichess clock value in milliseconds
+func clockDuration(milliseconds int64) time.Duration {
+	return time.Duration(milliseconds) * time.Millisecond
+}
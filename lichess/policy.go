@@ -0,0 +1,123 @@
+package lichess
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Policy decides which challenges the bot accepts and how it responds to offers during a game.
+// It is read from a JSON file, for example:
+//
+//	{
+//	  "variants": ["standard"],
+//	  "speeds": ["blitz", "rapid"],
+//	  "rated": true,
+//	  "casual": true,
+//	  "minInitial": 60,
+//	  "maxInitial": 1800,
+//	  "maxIncrement": 30,
+//	  "allowBots": false,
+//	  "blockUsers": ["someone"],
+//	  "maxGames": 1,
+//	  "acceptDraws": true,
+//	  "acceptTakebacks": false
+//	}
+//
+// Times are in seconds. An empty list allows anything, except for 'allowUsers', which when given
+// restricts challenges to the users listed.
+type Policy struct {
+	Variants     []string `json:"variants"`
+	Speeds       []string `json:"speeds"`
+	Rated        bool     `json:"rated"`
+	Casual       bool     `json:"casual"`
+	MinInitial   int      `json:"minInitial"`
+	MaxInitial   int      `json:"maxInitial"`
+	MaxIncrement int      `json:"maxIncrement"`
+	AllowBots    bool     `json:"allowBots"`
+	AllowUsers   []string `json:"allowUsers"`
+	BlockUsers   []string `json:"blockUsers"`
+	MaxGames     int      `json:"maxGames"`
+
+	// Draws are accepted only when the engine does not think it is winning
+	AcceptDraws     bool `json:"acceptDraws"`
+	AcceptTakebacks bool `json:"acceptTakebacks"`
+}
+
+// DefaultPolicy accepts standard, real-time games from humans, one at a time
+func DefaultPolicy() Policy {
+	return Policy{
+		Variants:   []string{"standard"},
+		Rated:      true,
+		Casual:     true,
+		MaxInitial: 3 * 60 * 60,
+		MaxGames:   1,
+	}
+}
+
+// LoadPolicy reads a policy file. Settings missing from the file keep their default values.
+func LoadPolicy(filename string) (Policy, error) {
+	policy := DefaultPolicy()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return policy, fmt.Errorf("error reading policy: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("error parsing policy file %s: %w", filename, err)
+	}
+
+	if policy.MaxGames < 1 {
+		return policy, fmt.Errorf("policy must allow at least one game")
+	}
+
+	return policy, nil
+}
+
+// Evaluate decides whether to accept a challenge. It returns an empty string to accept, or the
+// reason to give lichess for declining.
+func (p Policy) Evaluate(challenge Challenge, gamesInProgress int) string {
+	challenger := strings.ToLower(challenge.Challenger.ID)
+
+	switch {
+	case contains(p.BlockUsers, challenger):
+		return "generic"
+	case len(p.AllowUsers) > 0 && !contains(p.AllowUsers, challenger):
+		return "generic"
+	case challenge.Challenger.Title == "BOT" && !p.AllowBots:
+		return "noBot"
+	case len(p.Variants) > 0 && !contains(p.Variants, challenge.Variant.Key):
+		return "variant"
+	case challenge.Rated && !p.Rated:
+		return "casual"
+	case !challenge.Rated && !p.Casual:
+		return "rated"
+	case challenge.TimeControl.Type != "clock":
+		// Correspondence and unlimited games would leave the engine waiting indefinitely
+		return "timeControl"
+	case len(p.Speeds) > 0 && !contains(p.Speeds, challenge.Speed):
+		return "timeControl"
+	case challenge.TimeControl.Limit < p.MinInitial:
+		return "tooFast"
+	case p.MaxInitial > 0 && challenge.TimeControl.Limit > p.MaxInitial:
+		return "tooSlow"
+	case p.MaxIncrement > 0 && challenge.TimeControl.Increment > p.MaxIncrement:
+		return "tooSlow"
+	case gamesInProgress >= p.MaxGames:
+		return "later"
+	}
+
+	return ""
+}
+
+// contains reports whether the list holds the value, ignoring case
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
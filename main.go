@@ -2,21 +2,44 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	// Internal references
+	"goche/httpapi"
 	"goche/identification"
+	"goche/lichess"
 	"goche/logger"
-	"goche/uci"
+	"goche/protection"
+	"goche/protocol"
+	"goche/script"
+	"goche/server"
+	"goche/transcript"
+	"goche/utility"
 )
 
 func main() {
 	// Configure the small number of command line arguments
 	inputFile := flag.String("i", "", "filename of UCI commands for testing purposes")
+	continueFlag := flag.Bool("c", false, "continue reading commands from stdin after the input file")
+	transcriptFile := flag.String("t", "", "filename for a transcript of the session")
+	replayFile := flag.String("r", "", "filename of a transcript to replay and compare")
+	listenAddress := flag.String("listen", "", "accept sessions over TCP at this address (e.g. localhost:7777)")
+	maxSessions := flag.Int("sessions", server.DefaultMaxSessions, "maximum number of concurrent TCP sessions or HTTP analyses")
+	httpAddress := flag.String("http", "", "serve the HTTP/JSON analysis API at this address (e.g. localhost:8080)")
+	lichessPolicy := flag.String("lichess", "", "play on lichess as a bot, with this challenge policy file (token in LICHESS_TOKEN)")
+	lichessURL := flag.String("lichess-url", lichess.DefaultBaseURL, "base URL of the lichess API")
+	lichessBoard := flag.Bool("lichess-board", false, "use the lichess Board API rather than the Bot API")
+	idleTimeout := flag.Duration("idle", server.DefaultIdleTimeout, "close TCP sessions after this long without input")
 	logFile := flag.String("l", "", "filename for logging output")
 	debugFlag := flag.Bool("d", false, "enable debug logging")
 	helpFlag := flag.Bool("h", false, "show this help message and exit")
@@ -26,6 +49,16 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", filepath.Base(os.Args[0]))
 		fmt.Println("Options:")
 		fmt.Println("  -i filename	" + flag.Lookup("i").Usage)
+		fmt.Println("  -c   		" + flag.Lookup("c").Usage)
+		fmt.Println("  -t filename	" + flag.Lookup("t").Usage)
+		fmt.Println("  -r filename	" + flag.Lookup("r").Usage)
+		fmt.Println("  -listen addr	" + flag.Lookup("listen").Usage)
+		fmt.Println("  -sessions n	" + flag.Lookup("sessions").Usage)
+		fmt.Println("  -idle time	" + flag.Lookup("idle").Usage)
+		fmt.Println("  -http addr	" + flag.Lookup("http").Usage)
+		fmt.Println("  -lichess filename	" + flag.Lookup("lichess").Usage)
+		fmt.Println("  -lichess-url url	" + flag.Lookup("lichess-url").Usage)
+		fmt.Println("  -lichess-board	" + flag.Lookup("lichess-board").Usage)
 		fmt.Println("  -l filename	" + flag.Lookup("l").Usage)
 		fmt.Println("  -d   		" + flag.Lookup("d").Usage)
 		fmt.Println("  -v   		" + flag.Lookup("v").Usage)
@@ -62,37 +95,241 @@ func main() {
 		logger.SetOutput(logFile)
 	}
 
-	// We expect to take out input from stdin, but allow the user to specify an auto-response input file
-	var scanner *bufio.Scanner
-	if *inputFile == "" {
-		scanner = bufio.NewScanner(os.Stdin)
-	} else {
-		file, err := os.Open(*inputFile)
+	// The integrity check is made once, before any session can search. The engine applies the build's
+	// policy to every search if it fails.
+	if err := protection.Result(); err != nil {
+		logger.Error("Copy protection check failed: %s", err)
+	}
+
+	// In server mode, each connection is its own session and there is no console session
+	if *listenAddress != "" {
+		serve(*listenAddress, *maxSessions, *idleTimeout)
+		return
+	}
+
+	// Likewise in HTTP mode, where each analysis is independent
+	if *httpAddress != "" {
+		serveHTTP(*httpAddress, *maxSessions)
+		return
+	}
+
+	// And in lichess mode, where games are played through the lichess API
+	if *lichessPolicy != "" {
+		playLichess(*lichessPolicy, *lichessURL, *lichessBoard)
+		return
+	}
+
+	// All engine output goes through a single writer, with a copy of each line logged in debug mode
+	output := utility.NewStandardWriter()
+	if logger.DebugMode {
+		output.AddTap(func(line string) {
+			logger.Debug("Sent '%s'", line)
+		})
+	}
+
+	// Optionally record everything received and sent, so that a session can be reproduced
+	var recorder *transcript.Recorder
+	if *transcriptFile != "" {
+		file, err := os.Create(*transcriptFile)
+		if err != nil {
+			fmt.Println("Error creating transcript file:", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		recorder = transcript.NewRecorder(file)
+		output.AddTap(func(line string) {
+			recorder.Record(transcript.Outgoing, line)
+		})
+	}
+
+	// A replayed transcript takes the place of the input
+	var replay []transcript.Entry
+	var capture *transcript.Capture
+	if *replayFile != "" {
+		file, err := os.Open(*replayFile)
+		if err != nil {
+			fmt.Println("Error opening transcript file:", err)
+			os.Exit(1)
+		}
+
+		replay, err = transcript.Read(file)
+		file.Close()
+		if err != nil {
+			fmt.Println("Error reading transcript:", err)
+			os.Exit(1)
+		}
+
+		capture = transcript.NewCapture(output)
+	}
+
+	// We expect to take out input from stdin, but allow the user to specify an auto-response input file,
+	// which may contain directives that check the output
+	var testScript *script.Script
+	var file *os.File
+	if *inputFile != "" && capture == nil {
+		var err error
+		file, err = os.Open(*inputFile)
 		if err != nil {
 			fmt.Println("Error opening input file:", err)
 			os.Exit(1)
 		}
 		defer file.Close()
 
-		// Create a scanner to read from the file
-		scanner = bufio.NewScanner(file)
+		testScript = script.New(*inputFile, output)
 	}
 
-	// Create the environment for the UCI engine
-	uciConfiguration := uci.NewConfiguration()
+	// The protocol is chosen by the first command received
+	dispatcher := protocol.New(output)
 
-	// Input loop
-	for {
-		if !scanner.Scan() {
-			break
+	// Run the input file, then optionally carry on interactively
+	process := func(input string) bool {
+		if recorder != nil {
+			recorder.Record(transcript.Incoming, input)
 		}
+		return dispatcher.Process(input)
+	}
+
+	running := true
+	switch {
+	case capture != nil:
+		running = transcript.Replay(replay, process, capture)
+	case file != nil:
+		running = processInput(process, bufio.NewScanner(file), testScript)
+	}
+	if (file == nil && capture == nil) || (running && *continueFlag) {
+		processInput(process, bufio.NewScanner(os.Stdin), nil)
+	}
+
+	// Allow any search started by the input to conclude
+	dispatcher.Finish()
+
+	// Compare the replayed output with the transcript
+	if capture != nil {
+		differences := transcript.Diff(transcript.Lines(replay, transcript.Outgoing), capture.Lines())
+		for _, line := range differences {
+			fmt.Fprintln(os.Stderr, line)
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %s. %d differences\n", *replayFile, utility.If(len(differences) == 0, "MATCHED", "DIFFERED"), len(differences))
+		if len(differences) > 0 {
+			os.Exit(1)
+		}
+	}
+
+	// Report the outcome of the script's expectations
+	if testScript != nil {
+		for _, line := range testScript.Summary() {
+			fmt.Fprintln(os.Stderr, line)
+		}
+
+		if !testScript.Succeeded() {
+			file.Close()
+			os.Exit(1)
+		}
+	}
+}
+
+// serve runs engine sessions over TCP until interrupted
+func serve(address string, maxSessions int, idleTimeout time.Duration) {
+	tcpServer := server.New(maxSessions, idleTimeout)
+
+	listening, err := tcpServer.Listen(address)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		os.Exit(1)
+	}
+
+	// Report the address, which is useful when port 0 was given so that one would be chosen
+	fmt.Printf("Listening on %s\n", listening)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		logger.Debug("Interrupted, closing sessions")
+		tcpServer.Close()
+	}()
+
+	if err := tcpServer.Serve(); err != nil {
+		fmt.Println("Error accepting connections:", err)
+		os.Exit(1)
+	}
+}
+
+// serveHTTP runs the HTTP/JSON analysis API until interrupted
+func serveHTTP(address string, maxAnalyses int) {
+	api := httpapi.New(maxAnalyses)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Listening on http://%s\n", listener.Addr())
+
+	httpServer := &http.Server{Handler: api}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		logger.Debug("Interrupted, stopping analyses")
+
+		// Stopping the analyses ends their event streams, so that shutdown does not wait on them
+		api.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(ctx)
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println("Error serving HTTP:", err)
+		os.Exit(1)
+	}
+}
+
+// playLichess plays games on lichess until interrupted or the event stream ends
+func playLichess(policyFile string, baseURL string, boardAPI bool) {
+	policy, err := lichess.LoadPolicy(policyFile)
+	if err != nil {
+		fmt.Println("Error loading lichess policy:", err)
+		os.Exit(1)
+	}
+
+	client := lichess.NewClient(os.Getenv("LICHESS_TOKEN"), utility.If(boardAPI, lichess.BoardAPI, lichess.BotAPI))
+	client.BaseURL = baseURL
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := lichess.NewBridge(client, policy).Run(ctx); err != nil {
+		fmt.Println("Error playing on lichess:", err)
+		os.Exit(1)
+	}
+}
+
+// processInput passes each line read to the engine, carrying out any script directives along the way.
+// It returns false if the engine was told to quit.
+func processInput(process func(input string) bool, scanner *bufio.Scanner, testScript *script.Script) bool {
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
 
 		// Read the input
 		input := scanner.Text()
 
+		if testScript != nil && script.IsDirective(input) {
+			testScript.Execute(lineNumber, input)
+			continue
+		}
+
 		// Process commands until one of them tells us to break out of loop
-		if !uci.ProcessCommand(uciConfiguration, input) {
-			break
+		if !process(input) {
+			return false
 		}
 	}
 
@@ -100,4 +337,6 @@ func main() {
 	if err := scanner.Err(); err != nil {
 		fmt.Println("Error reading input:", err)
 	}
+
+	return true
 }
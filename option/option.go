@@ -0,0 +1,276 @@
+package option
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Type is one of the option types defined by the UCI protocol
+type Type string
+
+const (
+	Check  Type = "check"
+	Spin   Type = "spin"
+	Combo  Type = "combo"
+	Button Type = "button"
+	String Type = "string"
+)
+
+// The UCI convention for describing an empty string value
+const emptyString = "<empty>"
+
+// Option is a single engine option, as advertised in response to 'uci' and configured with 'setoption'
+type Option struct {
+	name         string
+	optionType   Type
+	defaultValue string
+	value        string
+	min          int
+	max          int
+	vars         []string
+	hidden       bool
+
+	// Called with the validated value whenever the option is set (or pressed, for a button)
+	onChange func(value string) error
+}
+
+// Name returns the name of the option as it is advertised
+func (o *Option) Name() string {
+	return o.name
+}
+
+// Type returns the UCI type of the option
+func (o *Option) Type() Type {
+	return o.optionType
+}
+
+// Hide keeps the option out of the list advertised to a GUI, while leaving it available to
+// 'setoption'. Hidden options are for tuning and testing rather than for users.
+func (o *Option) Hide() *Option {
+	o.hidden = true
+	return o
+}
+
+// Hidden reports whether the option is left out of the list advertised to a GUI
+func (o *Option) Hidden() bool {
+	return o.hidden
+}
+
+// Value returns the current value of the option as a string
+func (o *Option) Value() string {
+	return o.value
+}
+
+// Bool returns the current value of a check option
+func (o *Option) Bool() bool {
+	return o.value == "true"
+}
+
+// Int returns the current value of a spin option
+func (o *Option) Int() int {
+	value, _ := strconv.Atoi(o.value)
+	return value
+}
+
+// Declaration returns the option description that follows 'option' in response to 'uci'
+func (o *Option) Declaration() string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "name %s type %s", o.name, o.optionType)
+
+	switch o.optionType {
+	case Check, Combo:
+		fmt.Fprintf(&builder, " default %s", o.defaultValue)
+
+	case Spin:
+		fmt.Fprintf(&builder, " default %s min %d max %d", o.defaultValue, o.min, o.max)
+
+	case String:
+		if o.defaultValue == "" {
+			fmt.Fprintf(&builder, " default %s", emptyString)
+		} else {
+			fmt.Fprintf(&builder, " default %s", o.defaultValue)
+		}
+	}
+
+	for _, v := range o.vars {
+		fmt.Fprintf(&builder, " var %s", v)
+	}
+
+	return builder.String()
+}
+
+// validate checks the value against the option type and returns it in its canonical form
+func (o *Option) validate(value string) (string, error) {
+	switch o.optionType {
+	case Check:
+		switch strings.ToLower(value) {
+		case "true":
+			return "true", nil
+		case "false":
+			return "false", nil
+		}
+		return "", fmt.Errorf("option '%s' expects true or false, not '%s'", o.name, value)
+
+	case Spin:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("option '%s' expects a number, not '%s'", o.name, value)
+		}
+		if number < o.min || number > o.max {
+			return "", fmt.Errorf("option '%s' must be between %d and %d, not %d", o.name, o.min, o.max, number)
+		}
+		return strconv.Itoa(number), nil
+
+	case Combo:
+		for _, v := range o.vars {
+			if strings.EqualFold(v, value) {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("option '%s' does not allow '%s'", o.name, value)
+
+	case Button:
+		// Buttons carry no value
+		return "", nil
+
+	case String:
+		if value == emptyString {
+			return "", nil
+		}
+		return value, nil
+	}
+
+	return "", fmt.Errorf("option '%s' has unknown type '%s'", o.name, o.optionType)
+}
+
+// Registry holds the options of an engine in the order in which they were added
+type Registry struct {
+	options []*Option
+	byName  map[string]*Option
+}
+
+// NewRegistry creates an empty option registry
+func NewRegistry() *Registry {
+	return &Registry{
+		byName: make(map[string]*Option),
+	}
+}
+
+// AddCheck adds a boolean option
+func (r *Registry) AddCheck(name string, defaultValue bool, onChange func(value bool) error) *Option {
+	return r.add(&Option{
+		name:         name,
+		optionType:   Check,
+		defaultValue: strconv.FormatBool(defaultValue),
+		onChange: func(value string) error {
+			if onChange == nil {
+				return nil
+			}
+			return onChange(value == "true")
+		},
+	})
+}
+
+// AddSpin adds an integer option with an inclusive range
+func (r *Registry) AddSpin(name string, defaultValue int, min int, max int, onChange func(value int) error) *Option {
+	return r.add(&Option{
+		name:         name,
+		optionType:   Spin,
+		defaultValue: strconv.Itoa(defaultValue),
+		min:          min,
+		max:          max,
+		onChange: func(value string) error {
+			if onChange == nil {
+				return nil
+			}
+			number, _ := strconv.Atoi(value)
+			return onChange(number)
+		},
+	})
+}
+
+// AddCombo adds an option that takes one of a fixed set of values
+func (r *Registry) AddCombo(name string, defaultValue string, vars []string, onChange func(value string) error) *Option {
+	return r.add(&Option{
+		name:         name,
+		optionType:   Combo,
+		defaultValue: defaultValue,
+		vars:         vars,
+		onChange:     onChange,
+	})
+}
+
+// AddButton adds an option that performs an action when set
+func (r *Registry) AddButton(name string, onPress func() error) *Option {
+	return r.add(&Option{
+		name:       name,
+		optionType: Button,
+		onChange: func(_ string) error {
+			if onPress == nil {
+				return nil
+			}
+			return onPress()
+		},
+	})
+}
+
+// AddString adds a free text option
+func (r *Registry) AddString(name string, defaultValue string, onChange func(value string) error) *Option {
+	return r.add(&Option{
+		name:         name,
+		optionType:   String,
+		defaultValue: defaultValue,
+		onChange:     onChange,
+	})
+}
+
+func (r *Registry) add(o *Option) *Option {
+	key := strings.ToLower(o.name)
+	if _, exists := r.byName[key]; exists {
+		panic(fmt.Sprintf("option '%s' registered twice", o.name))
+	}
+
+	o.value = o.defaultValue
+
+	r.options = append(r.options, o)
+	r.byName[key] = o
+
+	return o
+}
+
+// Get returns the named option, matched without regard to case as the UCI protocol requires, or nil
+func (r *Registry) Get(name string) *Option {
+	return r.byName[strings.ToLower(name)]
+}
+
+// Options returns all options in the order in which they were added
+func (r *Registry) Options() []*Option {
+	return r.options
+}
+
+// Set validates and applies a new value to the named option, calling its change handler
+func (r *Registry) Set(name string, value string) error {
+	o := r.Get(name)
+	if o == nil {
+		return fmt.Errorf("unknown option '%s'", name)
+	}
+
+	canonical, err := o.validate(value)
+	if err != nil {
+		return err
+	}
+
+	if o.onChange != nil {
+		if err := o.onChange(canonical); err != nil {
+			return fmt.Errorf("option '%s' not changed: %w", o.name, err)
+		}
+	}
+
+	if o.optionType != Button {
+		o.value = canonical
+	}
+
+	return nil
+}
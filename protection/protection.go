@@ -0,0 +1,219 @@
+package protection
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// These may be replaced at build time. A build without a public key is a development build,
+// which is not checked. For example:
+//
+//	-ldflags "-X goche/protection.publicKey=<hex> -X goche/protection.policy=limited"
+var publicKey string = ""
+var policy string = string(PolicyWarn)
+
+// Policy is what the engine does when the integrity check fails
+type Policy string
+
+const (
+	// Warn the user, but otherwise play normally
+	PolicyWarn Policy = "warn"
+
+	// Play at limited strength
+	PolicyLimited Policy = "limited"
+
+	// Refuse to search
+	PolicyRefuse Policy = "refuse"
+)
+
+// The extension of a sidecar manifest, which sits alongside the executable
+const SidecarExtension = ".manifest"
+
+// An embedded manifest is appended to the executable as the manifest JSON, followed by its
+// length as a little-endian uint64, followed by this marker
+var trailerMarker = []byte("GOCHEMAN")
+
+const trailerFooterSize = 8 + 8
+
+// Manifest records the expected SHA-256 of an executable, signed when the executable was built
+type Manifest struct {
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// The outcome of the integrity check, which is made only once
+var (
+	checkOnce   sync.Once
+	checkResult error
+)
+
+// Required reports whether this build of the engine checks its own integrity
+func Required() bool {
+	return publicKey != ""
+}
+
+// GetPolicy returns the policy this build applies when the integrity check fails
+func GetPolicy() Policy {
+	switch Policy(policy) {
+	case PolicyLimited, PolicyRefuse:
+		return Policy(policy)
+	}
+
+	return PolicyWarn
+}
+
+// Result returns the outcome of checking the running executable, which is made the first time it is
+// asked for. It is nil if the executable is intact, or if this is a development build.
+func Result() error {
+	checkOnce.Do(func() {
+		if Required() {
+			checkResult = Check()
+		}
+	})
+
+	return checkResult
+}
+
+// Check verifies the running executable against its signed manifest, which is either embedded
+// in the executable or held in a sidecar file. It returns nil if the executable is intact.
+func Check() error {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("malformed public key")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to locate the executable: %w", err)
+	}
+
+	return Verify(executable, ed25519.PublicKey(key))
+}
+
+// Verify checks the named executable against its signed manifest using the public key
+func Verify(executable string, key ed25519.PublicKey) error {
+	content, err := os.ReadFile(executable)
+	if err != nil {
+		return fmt.Errorf("unable to read the executable: %w", err)
+	}
+
+	// Prefer an embedded manifest, in which case the hash covers everything before it
+	body, manifest, err := splitTrailer(content)
+	if err != nil {
+		return err
+	}
+
+	if manifest == nil {
+		manifest, err = readSidecar(executable + SidecarExtension)
+		if err != nil {
+			return err
+		}
+	}
+
+	signature, err := hex.DecodeString(manifest.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed manifest signature")
+	}
+
+	if !ed25519.Verify(key, []byte(manifest.SHA256), signature) {
+		return fmt.Errorf("manifest signature is not valid")
+	}
+
+	digest := sha256.Sum256(body)
+	if hex.EncodeToString(digest[:]) != manifest.SHA256 {
+		return fmt.Errorf("executable does not match its manifest")
+	}
+
+	return nil
+}
+
+// Sign creates a signed manifest for the executable, either embedding it in the executable
+// or writing it to a sidecar file. This is intended to be run as part of the build.
+func Sign(executable string, key ed25519.PrivateKey, embed bool) error {
+	content, err := os.ReadFile(executable)
+	if err != nil {
+		return fmt.Errorf("unable to read the executable: %w", err)
+	}
+
+	// Re-signing replaces any manifest that is already embedded
+	body, _, err := splitTrailer(content)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(body)
+	manifest := Manifest{
+		SHA256: hex.EncodeToString(digest[:]),
+	}
+	manifest.Signature = hex.EncodeToString(ed25519.Sign(key, []byte(manifest.SHA256)))
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+
+	if !embed {
+		return os.WriteFile(executable+SidecarExtension, data, 0644)
+	}
+
+	info, err := os.Stat(executable)
+	if err != nil {
+		return err
+	}
+
+	var trailer bytes.Buffer
+	trailer.Write(data)
+	binary.Write(&trailer, binary.LittleEndian, uint64(len(data)))
+	trailer.Write(trailerMarker)
+
+	return os.WriteFile(executable, append(body, trailer.Bytes()...), info.Mode())
+}
+
+// splitTrailer separates an embedded manifest from the executable content, if there is one
+func splitTrailer(content []byte) ([]byte, *Manifest, error) {
+	if len(content) < trailerFooterSize || !bytes.HasSuffix(content, trailerMarker) {
+		return content, nil, nil
+	}
+
+	footer := content[len(content)-trailerFooterSize:]
+	length := binary.LittleEndian.Uint64(footer[:8])
+	if length > uint64(len(content)-trailerFooterSize) {
+		return nil, nil, fmt.Errorf("malformed embedded manifest")
+	}
+
+	start := len(content) - trailerFooterSize - int(length)
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(content[start:len(content)-trailerFooterSize], manifest); err != nil {
+		return nil, nil, fmt.Errorf("malformed embedded manifest: %w", err)
+	}
+
+	return content[:start], manifest, nil
+}
+
+// readSidecar reads a manifest from the file alongside the executable
+func readSidecar(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no manifest found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %w", err)
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("malformed manifest: %w", err)
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	// Internal references
+	"goche/logger"
+	"goche/uci"
+	"goche/utility"
+	"goche/xboard"
+)
+
+// Dispatcher passes input to the front end for the protocol spoken by the GUI, which is XBoard's if
+// the first command is 'xboard' and UCI otherwise. Each dispatcher is an independent engine session.
+type Dispatcher struct {
+	output  *utility.Writer
+	process func(input string) bool
+	finish  func()
+	quit    func()
+}
+
+// New creates a dispatcher that will choose its protocol from the first command it receives
+func New(output *utility.Writer) *Dispatcher {
+	d := &Dispatcher{
+		output: output,
+		finish: func() {},
+		quit:   func() {},
+	}
+	d.process = d.detect
+
+	return d
+}
+
+// Process passes a line of input to the engine. It returns false if the engine was told to quit.
+func (d *Dispatcher) Process(input string) bool {
+	return d.process(input)
+}
+
+// Finish is called when input has ended, to let any search started by the input conclude
+func (d *Dispatcher) Finish() {
+	d.finish()
+}
+
+// Quit is called when the GUI has gone, to stop any search without waiting for its result
+func (d *Dispatcher) Quit() {
+	d.quit()
+}
+
+// detect selects the protocol from the first command, then passes that command on
+func (d *Dispatcher) detect(input string) bool {
+	tokens := utility.Tokenize(input)
+	if len(tokens) == 0 {
+		return true
+	}
+
+	if tokens[0] == "xboard" {
+		logger.Debug("Using the XBoard protocol")
+
+		session := xboard.NewSession(d.output)
+		d.process = func(input string) bool {
+			return xboard.ProcessCommand(session, input)
+		}
+		d.finish = func() {
+			xboard.Finish(session)
+		}
+		d.quit = func() {
+			xboard.Quit(session)
+		}
+	} else {
+		logger.Debug("Using the UCI protocol")
+
+		configuration := uci.NewConfiguration(d.output)
+		d.process = func(input string) bool {
+			return uci.ProcessCommand(configuration, input)
+		}
+		d.finish = func() {
+			uci.Finish(configuration)
+		}
+		d.quit = func() {
+			uci.Quit(configuration)
+		}
+	}
+
+	return d.process(input)
+}
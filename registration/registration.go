@@ -0,0 +1,149 @@
+package registration
+
+import (
+	"crypto/ed25519"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// Internal references
+	"goche/identification"
+)
+
+// The hex-encoded ed25519 public key that registration codes are checked with. It is empty in
+// development builds, which do not require registration, and is set for distribution builds with:
+//
+//	-ldflags "-X goche/registration.publicKey=<hex>"
+//
+// Codes are issued with the matching private key by tools/regcode, which is not part of the engine.
+var publicKey string = ""
+
+// Codes are written in base32 without padding, in groups of this many characters
+var codeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+const codeGroupSize = 4
+
+// The name of the file, within the per-user configuration directory, that holds the registration
+const registrationFilename = "registration.json"
+
+// Registration is the name and code a user registered with
+type Registration struct {
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+// Required reports whether this build of the engine needs to be registered
+func Required() bool {
+	return publicKey != ""
+}
+
+// Sign creates the registration code for a name with the private key. It is used by the tool that
+// issues codes; the engine itself only holds the public key.
+func Sign(name string, key ed25519.PrivateKey) string {
+	encoded := codeEncoding.EncodeToString(ed25519.Sign(key, []byte(normalizeName(name))))
+
+	// Group the characters to make the code easier to read and type
+	var groups []string
+	for len(encoded) > codeGroupSize {
+		groups = append(groups, encoded[:codeGroupSize])
+		encoded = encoded[codeGroupSize:]
+	}
+	groups = append(groups, encoded)
+
+	return strings.Join(groups, "-")
+}
+
+// Verify checks, offline, that the registration's code was issued for its name
+func (r *Registration) Verify() bool {
+	if r == nil || normalizeName(r.Name) == "" {
+		return false
+	}
+
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+
+	signature, err := codeEncoding.DecodeString(normalizeCode(r.Code))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+
+	return ed25519.Verify(ed25519.PublicKey(key), []byte(normalizeName(r.Name)), signature)
+}
+
+// Load reads the registration stored for the current user. It returns nil, without an error,
+// if the user has not registered.
+func Load() (*Registration, error) {
+	path, err := filePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading registration: %w", err)
+	}
+
+	registration := &Registration{}
+	if err := json.Unmarshal(data, registration); err != nil {
+		return nil, fmt.Errorf("error parsing registration file %s: %w", path, err)
+	}
+
+	return registration, nil
+}
+
+// Save stores the registration for the current user, to be read the next time the engine starts
+func (r *Registration) Save() error {
+	path, err := filePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating registration directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding registration: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing registration: %w", err)
+	}
+
+	return nil
+}
+
+// filePath returns the location of the per-user registration file
+func filePath() (string, error) {
+	directory, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate the user configuration directory: %w", err)
+	}
+
+	return filepath.Join(directory, identification.GetEngineName(), registrationFilename), nil
+}
+
+// normalizeName makes names that differ only in case or spacing equivalent
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// normalizeCode removes the grouping and case from a code as it might be typed by a user
+func normalizeCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return code
+}
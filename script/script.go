@@ -0,0 +1,224 @@
+package script
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	// Internal references
+	"goche/utility"
+)
+
+// The prefix that marks a line of a script as a directive or a comment rather than a UCI command
+const directivePrefix = "#"
+
+// How long a script waits for expected output unless told otherwise with '#timeout'
+const DefaultTimeout = 5 * time.Second
+
+// Failure records an expectation that was not met
+type Failure struct {
+	Line    int
+	Message string
+}
+
+// Script checks the engine output against the expectations of a '.uci' test file. Each line of the
+// file is either a UCI command, which is passed to the engine, or one of the following directives:
+//
+//	#expect <regex>    wait for an output line matching the regular expression
+//	#wait <keyword>    wait for an output line that starts with the keyword, e.g. 'bestmove'
+//	#timeout <dur>     how long '#expect' and '#wait' should wait, e.g. '500ms' or '5s'
+//	#sleep <dur>       pause before reading the next line
+//	# <text>           a comment
+//
+// Output is consumed as it is matched, so each expectation looks at the output that followed the
+// previous one.
+type Script struct {
+	name    string
+	timeout time.Duration
+
+	mutex    sync.Mutex
+	pending  []string
+	arrived  chan struct{}
+	passed   int
+	failures []Failure
+}
+
+// New creates a script with the given name, watching the output of the engine
+func New(name string, output *utility.Writer) *Script {
+	s := &Script{
+		name:    name,
+		timeout: DefaultTimeout,
+		arrived: make(chan struct{}, 1),
+	}
+
+	output.AddTap(s.receive)
+
+	return s
+}
+
+// IsDirective reports whether a line of a script is a directive or comment rather than a command
+func IsDirective(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), directivePrefix)
+}
+
+// Execute carries out a directive from the given line of the script
+func (s *Script) Execute(lineNumber int, line string) {
+	body := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), directivePrefix))
+
+	tokens := utility.Tokenize(body)
+	if len(tokens) == 0 {
+		return
+	}
+
+	// The argument is the rest of the line, kept intact since an expression may contain spaces
+	directive := tokens[0]
+	argument := strings.TrimSpace(body[len(directive):])
+
+	switch directive {
+	case "expect":
+		pattern, err := regexp.Compile(argument)
+		if err != nil {
+			s.fail(lineNumber, "invalid expression '%s': %s", argument, err)
+			return
+		}
+		s.await(lineNumber, fmt.Sprintf("output matching '%s'", argument), pattern.MatchString)
+
+	case "wait":
+		if argument == "" {
+			s.fail(lineNumber, "'#wait' needs a keyword")
+			return
+		}
+		s.await(lineNumber, fmt.Sprintf("'%s'", argument), func(output string) bool {
+			fields := utility.Tokenize(output)
+			return len(fields) > 0 && fields[0] == argument
+		})
+
+	case "timeout":
+		duration, err := time.ParseDuration(argument)
+		if err != nil || duration <= 0 {
+			s.fail(lineNumber, "invalid timeout '%s'", argument)
+			return
+		}
+		s.timeout = duration
+
+	case "sleep":
+		duration, err := time.ParseDuration(argument)
+		if err != nil || duration < 0 {
+			s.fail(lineNumber, "invalid sleep '%s'", argument)
+			return
+		}
+		time.Sleep(duration)
+
+	default:
+		// Anything else is a comment
+	}
+}
+
+// Passed returns the number of expectations that were met
+func (s *Script) Passed() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.passed
+}
+
+// Failures returns the expectations that were not met, in the order they were checked
+func (s *Script) Failures() []Failure {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Failure(nil), s.failures...)
+}
+
+// Succeeded reports whether every expectation was met
+func (s *Script) Succeeded() bool {
+	return len(s.Failures()) == 0
+}
+
+// Summary describes the result of the script, one line per failure followed by the totals
+func (s *Script) Summary() []string {
+	failures := s.Failures()
+
+	var lines []string
+	for _, failure := range failures {
+		lines = append(lines, fmt.Sprintf("%s:%d: FAILED %s", s.name, failure.Line, failure.Message))
+	}
+
+	result := utility.If(len(failures) == 0, "PASSED", "FAILED")
+	lines = append(lines, fmt.Sprintf("%s: %s. %d passed, %d failed", s.name, result, s.Passed(), len(failures)))
+
+	return lines
+}
+
+// receive is the output tap, which queues each line written by the engine
+func (s *Script) receive(line string) {
+	s.mutex.Lock()
+	s.pending = append(s.pending, line)
+	s.mutex.Unlock()
+
+	// Wake any waiting expectation without blocking the writer
+	select {
+	case s.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// await consumes output until a line satisfies the match, or the timeout expires
+func (s *Script) await(lineNumber int, description string, match func(output string) bool) {
+	deadline := time.NewTimer(s.timeout)
+	defer deadline.Stop()
+
+	for {
+		if s.consume(match) {
+			s.pass()
+			return
+		}
+
+		select {
+		case <-s.arrived:
+		case <-deadline.C:
+			// Check once more in case the line arrived just as the deadline expired
+			if s.consume(match) {
+				s.pass()
+				return
+			}
+			s.fail(lineNumber, "no %s within %s", description, s.timeout)
+			return
+		}
+	}
+}
+
+// consume discards queued output up to and including the first matching line, returning whether
+// there was one. Output that does not match is discarded as well.
+func (s *Script) consume(match func(output string) bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, line := range s.pending {
+		if match(line) {
+			s.pending = s.pending[i+1:]
+			return true
+		}
+	}
+
+	s.pending = s.pending[:0]
+	return false
+}
+
+// pass records a met expectation
+func (s *Script) pass() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.passed++
+}
+
+// fail records an unmet expectation
+func (s *Script) fail(lineNumber int, format string, args ...interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures = append(s.failures, Failure{Line: lineNumber, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	// Internal references
+	"goche/logger"
+	"goche/protocol"
+	"goche/utility"
+)
+
+// Defaults for the number of concurrent sessions and how long a session may go without input
+const (
+	DefaultMaxSessions = 4
+	DefaultIdleTimeout = 30 * time.Minute
+)
+
+// Server accepts TCP connections and runs an independent engine session on each. Sessions share
+// nothing but the engine's read-only tables; each has its own options, position and search.
+type Server struct {
+	maxSessions int
+	idleTimeout time.Duration
+
+	// A slot is taken from here for each session, which limits how many run at once
+	slots chan struct{}
+
+	mutex       sync.Mutex
+	listener    net.Listener
+	connections map[net.Conn]struct{}
+	closed      bool
+	sessions    sync.WaitGroup
+}
+
+// New creates a server allowing the given number of concurrent sessions, each of which is closed
+// if it receives no input for the idle timeout
+func New(maxSessions int, idleTimeout time.Duration) *Server {
+	if maxSessions < 1 {
+		maxSessions = DefaultMaxSessions
+	}
+
+	return &Server{
+		maxSessions: maxSessions,
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, maxSessions),
+		connections: make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen opens the TCP address, e.g. 'localhost:7777', ready to Serve. Use port 0 to have one chosen.
+func (s *Server) Listen(address string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.listener = listener
+	s.mutex.Unlock()
+
+	return listener.Addr(), nil
+}
+
+// Serve accepts connections until the server is closed. It returns nil after Close.
+func (s *Server) Serve() error {
+	for {
+		connection, err := s.listener.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		// Turn away connections beyond the limit rather than leaving them waiting
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			logger.Warn("Refused connection from %s: %d sessions already running", connection.RemoteAddr(), s.maxSessions)
+			connection.Write([]byte("Too many sessions\n"))
+			connection.Close()
+			continue
+		}
+
+		if !s.track(connection) {
+			<-s.slots
+			connection.Close()
+			return nil
+		}
+
+		go s.run(connection)
+	}
+}
+
+// Close stops accepting connections, closes every session, stopping any search it is running, and
+// waits for them to finish
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+
+	for connection := range s.connections {
+		connection.Close()
+	}
+	s.mutex.Unlock()
+
+	s.sessions.Wait()
+
+	return err
+}
+
+// track records an open connection, so that Close can end its session. It returns false if the
+// server is closing.
+func (s *Server) track(connection net.Conn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.connections[connection] = struct{}{}
+	s.sessions.Add(1)
+
+	return true
+}
+
+// run is the body of a session goroutine, reading commands from the connection until it closes,
+// the engine is told to quit or the session is idle for too long
+func (s *Server) run(connection net.Conn) {
+	defer func() {
+		s.mutex.Lock()
+		delete(s.connections, connection)
+		s.mutex.Unlock()
+
+		connection.Close()
+		<-s.slots
+		s.sessions.Done()
+	}()
+
+	remote := connection.RemoteAddr()
+	logger.Debug("Session started for %s", remote)
+
+	output := utility.NewWriter(connection)
+	dispatcher := protocol.New(output)
+
+	scanner := bufio.NewScanner(connection)
+	for {
+		if s.idleTimeout > 0 {
+			connection.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		}
+
+		if !scanner.Scan() {
+			break
+		}
+
+		if !dispatcher.Process(scanner.Text()) {
+			break
+		}
+	}
+
+	if err := scanner.Err(); errors.Is(err, os.ErrDeadlineExceeded) {
+		logger.Warn("Session for %s closed after %s without input", remote, s.idleTimeout)
+	} else if err != nil {
+		logger.Debug("Session for %s ended: %s", remote, err)
+	}
+
+	// With the connection gone there is no one to report a result to, so the search is stopped
+	// rather than left to hold the session's slot until it finishes
+	dispatcher.Quit()
+
+	logger.Debug("Session ended for %s", remote)
+}
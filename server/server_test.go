@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer serves on a loopback port chosen by the system, closing the server when the test ends
+func startServer(t *testing.T, maxSessions int, idleTimeout time.Duration) (*Server, string) {
+	t.Helper()
+
+	s := New(maxSessions, idleTimeout)
+	address, err := s.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %s", err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve()
+	}()
+
+	// A session left searching would keep Close waiting, so give up on it rather than hang the tests
+	t.Cleanup(func() {
+		closed := make(chan struct{})
+		go func() {
+			s.Close()
+			close(closed)
+		}()
+
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Errorf("Sessions still running 5s after Close")
+		}
+
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %s", err)
+		}
+	})
+
+	return s, address.String()
+}
+
+// client is one end of a session, reading the engine's output line by line
+type client struct {
+	t          *testing.T
+	connection net.Conn
+	reader     *bufio.Reader
+}
+
+func dial(t *testing.T, address string) *client {
+	t.Helper()
+
+	connection, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("Dial: %s", err)
+	}
+	t.Cleanup(func() { connection.Close() })
+
+	return &client{t: t, connection: connection, reader: bufio.NewReader(connection)}
+}
+
+func (c *client) send(command string) {
+	c.t.Helper()
+
+	if _, err := c.connection.Write([]byte(command + "\n")); err != nil {
+		c.t.Fatalf("Sending '%s': %s", command, err)
+	}
+}
+
+// expect reads lines until one starts with the prefix, failing if none does within the timeout
+func (c *client) expect(prefix string, timeout time.Duration) {
+	c.t.Helper()
+
+	c.connection.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		line, err := c.reader.ReadString('\n')
+		if strings.HasPrefix(line, prefix) {
+			return
+		}
+		if err != nil {
+			c.t.Fatalf("Waiting for '%s': %s", prefix, err)
+		}
+	}
+}
+
+// expectClosed fails unless the server closes the connection within the timeout
+func (c *client) expectClosed(timeout time.Duration) {
+	c.t.Helper()
+
+	c.connection.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		if _, err := c.reader.ReadString('\n'); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				c.t.Fatalf("Connection still open after %s", timeout)
+			}
+			return
+		}
+	}
+}
+
+// connectWhenFree dials until the server accepts a session, as a freed slot is released asynchronously
+func connectWhenFree(t *testing.T, address string, timeout time.Duration) *client {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		c := dial(t, address)
+		c.send("isready")
+
+		// A refused connection is told so and closed, while a session greets the client first
+		c.connection.SetReadDeadline(time.Now().Add(time.Second))
+		var line string
+		for !strings.HasPrefix(line, "Too many sessions") {
+			var err error
+			if line, err = c.reader.ReadString('\n'); err != nil {
+				break
+			}
+			if strings.HasPrefix(line, "readyok") {
+				return c
+			}
+		}
+
+		c.connection.Close()
+		if time.Now().After(deadline) {
+			t.Fatalf("No session slot free after %s, last response %q", timeout, line)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestSessionLimit(t *testing.T) {
+	_, address := startServer(t, 1, 0)
+
+	first := dial(t, address)
+	first.send("isready")
+	first.expect("readyok", 5*time.Second)
+
+	second := dial(t, address)
+	second.expect("Too many sessions", 5*time.Second)
+	second.expectClosed(5 * time.Second)
+
+	first.send("quit")
+	first.expectClosed(5 * time.Second)
+
+	connectWhenFree(t, address, 5*time.Second)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	_, address := startServer(t, 1, 200*time.Millisecond)
+
+	c := dial(t, address)
+	c.send("isready")
+	c.expect("readyok", 5*time.Second)
+
+	c.expectClosed(5 * time.Second)
+
+	// The slot is released, however long the session had to run
+	connectWhenFree(t, address, 5*time.Second)
+}
+
+func TestIdleTimeoutStopsSearch(t *testing.T) {
+	_, address := startServer(t, 1, 300*time.Millisecond)
+
+	c := dial(t, address)
+	c.send("go depth 60")
+	c.expect("info depth 1 ", 5*time.Second)
+
+	c.expectClosed(5 * time.Second)
+	connectWhenFree(t, address, 5*time.Second)
+}
+
+func TestDisconnectStopsSearch(t *testing.T) {
+	for _, protocol := range []string{"uci", "xboard"} {
+		t.Run(protocol, func(t *testing.T) {
+			_, address := startServer(t, 1, 0)
+
+			c := dial(t, address)
+			if protocol == "xboard" {
+				c.send("xboard")
+				c.send("protover 2")
+				c.expect("feature", 5*time.Second)
+				c.send("analyze")
+			} else {
+				c.send("go depth 60")
+			}
+			c.expect("", time.Second)
+
+			// A search left running would hold the only slot until it reached depth 60
+			c.connection.Close()
+			connectWhenFree(t, address, 5*time.Second).send("quit")
+		})
+	}
+}
+
+func TestCloseStopsSearches(t *testing.T) {
+	s, address := startServer(t, 2, 0)
+
+	for i := 0; i < 2; i++ {
+		c := dial(t, address)
+		c.send("go depth 60")
+		c.expect("info depth 1 ", 5*time.Second)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not stop the sessions' searches")
+	}
+}
@@ -0,0 +1,53 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Flags registers the -k and -genkey flags shared by the tools that sign with an Ed25519 key
+func Flags() (keyFile *string, genkey *bool) {
+	keyFile = flag.String("k", "", "filename of the hex-encoded private signing key")
+	genkey = flag.Bool("genkey", false, "generate a new key pair and exit")
+	return keyFile, genkey
+}
+
+// PrintUsage writes the usage of the flags registered by Flags
+func PrintUsage() {
+	fmt.Println("  -k filename	" + flag.Lookup("k").Usage)
+	fmt.Println("  -genkey   	" + flag.Lookup("genkey").Usage)
+}
+
+// Generate creates a new key pair and prints both keys in hex, then exits
+func Generate() {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		fmt.Println("Error generating key:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("public  %s\n", hex.EncodeToString(public))
+	fmt.Printf("private %s\n", hex.EncodeToString(private))
+	os.Exit(0)
+}
+
+// ReadPrivate reads a hex-encoded private key as printed by Generate, exiting if it cannot
+func ReadPrivate(filename string) ed25519.PrivateKey {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Println("Error reading key file:", err)
+		os.Exit(1)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		fmt.Println("Error reading key file: malformed private key")
+		os.Exit(1)
+	}
+
+	return ed25519.PrivateKey(key)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lichessstub stands in for lichess when testing the engine's lichess bridge. It replays recorded
+// NDJSON event and game streams, and prints each request the bridge makes in response, e.g.
+//
+//	lichessstub -events test/lichess/events.ndjson -games test/lichess &
+//	goche -lichess test/lichess/policy.json -lichess-url http://localhost:9999
+func main() {
+	address := flag.String("addr", "localhost:9999", "address to listen on")
+	eventsFile := flag.String("events", "", "filename of the recorded event stream")
+	gamesDirectory := flag.String("games", ".", "directory of recorded game streams, named <game id>.ndjson")
+	account := flag.String("account", "goche", "id of the account the bridge plays as")
+	delay := flag.Duration("delay", 50*time.Millisecond, "delay before each line of a stream")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", filepath.Base(os.Args[0]))
+		fmt.Println("Options:")
+		fmt.Println("  -addr address	" + flag.Lookup("addr").Usage)
+		fmt.Println("  -events filename	" + flag.Lookup("events").Usage)
+		fmt.Println("  -games directory	" + flag.Lookup("games").Usage)
+		fmt.Println("  -account id	" + flag.Lookup("account").Usage)
+		fmt.Println("  -delay duration	" + flag.Lookup("delay").Usage)
+	}
+
+	flag.Parse()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/account", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\"id\":%q,\"username\":%q}\n", *account, *account)
+	})
+
+	mux.HandleFunc("GET /api/stream/event", func(w http.ResponseWriter, r *http.Request) {
+		replay(w, *eventsFile, *delay)
+	})
+
+	mux.HandleFunc("GET /api/{api}/game/stream/{id}", func(w http.ResponseWriter, r *http.Request) {
+		replay(w, filepath.Join(*gamesDirectory, r.PathValue("id")+".ndjson"), *delay)
+	})
+
+	// Everything the bridge posts is printed, in a stable form that can be compared between runs
+	mux.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		var fields []string
+		for key, values := range r.PostForm {
+			fields = append(fields, key+"="+strings.Join(values, ","))
+		}
+		sort.Strings(fields)
+
+		fmt.Printf("POST %s %s\n", r.URL.Path, strings.Join(fields, " "))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, "{\"ok\":true}")
+	})
+
+	listener, err := net.Listen("tcp", *address)
+	if err != nil {
+		fmt.Println("Error listening:", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", listener.Addr())
+
+	if err := http.Serve(listener, mux); err != nil {
+		fmt.Println("Error serving:", err)
+		os.Exit(1)
+	}
+}
+
+// replay writes a recorded NDJSON stream a line at a time, then ends it
+func replay(w http.ResponseWriter, filename string, delay time.Duration) {
+	file, err := os.Open(filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	controller := http.NewResponseController(w)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		time.Sleep(delay)
+
+		fmt.Fprintln(w, scanner.Text())
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	// Internal references
+	"goche/protection"
+	"goche/tools/internal/keys"
+)
+
+// manifest signs an engine executable at build time so that it can check its own integrity.
+// The public key it prints with -genkey is built into the engine with:
+//
+//	-ldflags "-X goche/protection.publicKey=<hex>"
+func main() {
+	keyFile, genkeyFlag := keys.Flags()
+	embedFlag := flag.Bool("e", false, "embed the manifest in the executable rather than writing a sidecar file")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] executable\n", filepath.Base(os.Args[0]))
+		fmt.Println("Options:")
+		keys.PrintUsage()
+		fmt.Println("  -e   		" + flag.Lookup("e").Usage)
+	}
+
+	flag.Parse()
+
+	// Handle -genkey
+	if *genkeyFlag {
+		keys.Generate()
+	}
+
+	if *keyFile == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	key := keys.ReadPrivate(*keyFile)
+
+	if err := protection.Sign(flag.Arg(0), key, *embedFlag); err != nil {
+		fmt.Println("Error signing executable:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// Internal references
+	"goche/registration"
+	"goche/tools/internal/keys"
+)
+
+// regcode issues the registration code for a user's name. The private key never leaves the
+// machine codes are issued on; the public key it prints with -genkey is built into the engine with:
+//
+//	-ldflags "-X goche/registration.publicKey=<hex>"
+func main() {
+	keyFile, genkeyFlag := keys.Flags()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -k filename name\n", filepath.Base(os.Args[0]))
+		fmt.Println("Options:")
+		keys.PrintUsage()
+	}
+
+	flag.Parse()
+
+	// Handle -genkey
+	if *genkeyFlag {
+		keys.Generate()
+	}
+
+	if *keyFile == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	key := keys.ReadPrivate(*keyFile)
+
+	// A name may be given as several arguments, rather than quoted
+	fmt.Println(registration.Sign(strings.Join(flag.Args(), " "), key))
+}
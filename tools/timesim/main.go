@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// Internal references
+	"goche/engine"
+)
+
+// timesim replays recorded searches under simulated clocks, to show how the time manager would share
+// out the time in games at different time controls without playing them. The searches are recorded
+// first with -record, from a file of games, each iteration's time, best move and score being kept.
+//
+//	timesim -record games.txt -movetime 10s > traces.ndjson
+//	timesim -tc 60+0.6,300+3,40/120 traces.ndjson
+func main() {
+	recordFile := flag.String("record", "", "record traces of searches of the games in the file, one per line")
+	moveTime := flag.Duration("movetime", 10*time.Second, "the time to search each position for when recording")
+	depth := flag.Int("depth", 0, "the depth to search each position to when recording, rather than for a time")
+	timeControls := flag.String("tc", "60+0.6,300+3,40/120", "comma-separated time controls, as [moves/]base[+increment] in seconds")
+	moveOverhead := flag.Duration("overhead", 30*time.Millisecond, "the move overhead the time manager keeps back")
+	latency := flag.Duration("latency", 0, "the time lost on the clock for each move beyond the search")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] tracefile\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "       %s -record gamefile [-movetime duration | -depth n] > tracefile\n", filepath.Base(os.Args[0]))
+		fmt.Println("Options:")
+		fmt.Println("  -record filename	" + flag.Lookup("record").Usage)
+		fmt.Println("  -movetime duration	" + flag.Lookup("movetime").Usage)
+		fmt.Println("  -depth n		" + flag.Lookup("depth").Usage)
+		fmt.Println("  -tc list		" + flag.Lookup("tc").Usage)
+		fmt.Println("  -overhead duration	" + flag.Lookup("overhead").Usage)
+		fmt.Println("  -latency duration	" + flag.Lookup("latency").Usage)
+	}
+
+	flag.Parse()
+
+	if *recordFile != "" {
+		limits := engine.Limits{MoveTime: *moveTime}
+		if *depth > 0 {
+			limits = engine.Limits{Depth: *depth}
+		}
+
+		if err := recordTraces(*recordFile, os.Stdout, limits); err != nil {
+			fmt.Println("Error recording traces:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	games, err := readTraces(flag.Arg(0))
+	if err != nil {
+		fmt.Println("Error reading traces:", err)
+		os.Exit(1)
+	}
+
+	for i, text := range strings.Split(*timeControls, ",") {
+		tc, err := parseTimeControl(strings.TrimSpace(text))
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		s := &simulation{tc: tc, moveOverhead: *moveOverhead, latency: *latency}
+		for _, moves := range games {
+			if err := s.playGame(moves); err != nil {
+				fmt.Println("Error replaying game:", err)
+				os.Exit(1)
+			}
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		s.report()
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+)
+
+// timeControl is a clock of base time plus an increment per move, with the base time given again
+// after every moves moves when moves is not zero
+type timeControl struct {
+	text      string
+	moves     int
+	base      time.Duration
+	increment time.Duration
+}
+
+// parseTimeControl reads a time control in the form [moves/]base[+increment], in seconds
+func parseTimeControl(text string) (timeControl, error) {
+	tc := timeControl{text: text}
+	rest := text
+
+	if moves, after, found := strings.Cut(rest, "/"); found {
+		n, err := strconv.Atoi(moves)
+		if err != nil || n <= 0 {
+			return tc, fmt.Errorf("invalid number of moves in time control %q", text)
+		}
+		tc.moves = n
+		rest = after
+	}
+
+	base, increment, hasIncrement := strings.Cut(rest, "+")
+
+	var err error
+	if tc.base, err = parseSeconds(base); err != nil || tc.base <= 0 {
+		return tc, fmt.Errorf("invalid base time in time control %q", text)
+	}
+	if hasIncrement {
+		if tc.increment, err = parseSeconds(increment); err != nil {
+			return tc, fmt.Errorf("invalid increment in time control %q", text)
+		}
+	}
+
+	return tc, nil
+}
+
+func parseSeconds(text string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(text, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// The game phases time use is reported for, by the evaluation's phase out of its maximum
+var phaseNames = []string{"opening", "middlegame", "endgame"}
+
+func phaseOf(board *chess.Board) int {
+	terms := engine.EvaluateTerms(board)
+	switch {
+	case terms.Phase*4 >= terms.MaxPhase*3:
+		return 0
+	case terms.Phase*4 >= terms.MaxPhase:
+		return 1
+	}
+	return 2
+}
+
+// How the simulated search for a move came to an end
+const (
+	stoppedEarly = iota
+	stoppedAtMaximum
+	traceExhausted
+	stopReasons
+)
+
+// simulation collects the results of replaying the games under one time control
+type simulation struct {
+	tc           timeControl
+	moveOverhead time.Duration
+	latency      time.Duration
+
+	phaseTime  [3]time.Duration
+	phaseMoves [3]int
+	stops      [stopReasons]int
+
+	games     int
+	flagFalls []string
+	timeLeft  []time.Duration
+}
+
+// playGame replays the searches of a game under the simulated clocks, until its end or a flag falls
+func (s *simulation) playGame(moves []moveTrace) error {
+	s.games++
+
+	var clocks [2]time.Duration
+	var movesPlayed [2]int
+	clocks[0], clocks[1] = s.tc.base, s.tc.base
+
+	for _, trace := range moves {
+		board, err := chess.NewBoard(trace.Fen)
+		if err != nil {
+			return fmt.Errorf("game %d ply %d: %w", trace.Game, trace.Ply, err)
+		}
+
+		side := 0
+		if !board.IsWhiteToMove() {
+			side = 1
+		}
+
+		limits := engine.Limits{
+			WhiteTime:      clocks[0],
+			BlackTime:      clocks[1],
+			WhiteIncrement: s.tc.increment,
+			BlackIncrement: s.tc.increment,
+			Clock:          true,
+		}
+		if s.tc.moves > 0 {
+			limits.MovesToGo = s.tc.moves - movesPlayed[side]%s.tc.moves
+		}
+
+		used, reason, err := s.searchTime(trace, board, limits)
+		if err != nil {
+			return err
+		}
+
+		phase := phaseOf(board)
+		s.phaseTime[phase] += used
+		s.phaseMoves[phase]++
+		s.stops[reason]++
+
+		clocks[side] -= used + s.latency
+		if clocks[side] < 0 {
+			s.flagFalls = append(s.flagFalls, fmt.Sprintf("game %d ply %d, %s over", trace.Game, trace.Ply, (-clocks[side]).Round(time.Millisecond)))
+			s.timeLeft = append(s.timeLeft, clocks[side])
+			return nil
+		}
+
+		clocks[side] += s.tc.increment
+		movesPlayed[side]++
+		if s.tc.moves > 0 && movesPlayed[side]%s.tc.moves == 0 {
+			clocks[side] += s.tc.base
+		}
+	}
+
+	s.timeLeft = append(s.timeLeft, min(clocks[0], clocks[1]))
+	return nil
+}
+
+// searchTime returns the time the search recorded in the trace would have used under the limits, and
+// why it would have stopped. The time manager is given the iterations as the search would give them.
+func (s *simulation) searchTime(trace moveTrace, board *chess.Board, limits engine.Limits) (time.Duration, int, error) {
+	tm := engine.NewTimeManager(limits, board, s.moveOverhead, false)
+
+	moveList, err := board.GetMoves(make([]chess.Move, 0, 256))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i, it := range trace.Iterations {
+		elapsed := it.elapsed()
+
+		// The first iteration always completes, and a later one only within the maximum
+		if i > 0 && tm.Expired(elapsed) {
+			return tm.Maximum(), stoppedAtMaximum, nil
+		}
+
+		bestMove, err := board.FindMove(it.BestMove)
+		if err != nil {
+			return 0, 0, fmt.Errorf("game %d ply %d: %w", trace.Game, trace.Ply, err)
+		}
+		tm.Update(bestMove, it.score())
+
+		if tm.Timed() && len(moveList) == 1 || !tm.StartIteration(elapsed) {
+			return elapsed, stoppedEarly, nil
+		}
+	}
+
+	// The search recorded was shorter than the time manager wanted, so the time used is at least this
+	return trace.Iterations[len(trace.Iterations)-1].elapsed(), traceExhausted, nil
+}
+
+// report prints the results of the simulation
+func (s *simulation) report() {
+	fmt.Printf("Time control %s, move overhead %s, latency %s\n", s.tc.text, s.moveOverhead, s.latency)
+
+	var total time.Duration
+	for _, used := range s.phaseTime {
+		total += used
+	}
+
+	fmt.Println("  Time used by game phase:")
+	for phase, name := range phaseNames {
+		average := time.Duration(0)
+		if s.phaseMoves[phase] > 0 {
+			average = s.phaseTime[phase] / time.Duration(s.phaseMoves[phase])
+		}
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(s.phaseTime[phase]) / float64(total)
+		}
+		fmt.Printf("    %-10s  %4d moves  %10s total  %10s per move  %5.1f%%\n", name, s.phaseMoves[phase],
+			s.phaseTime[phase].Round(time.Millisecond), average.Round(time.Millisecond), share)
+	}
+
+	moves := s.stops[stoppedEarly] + s.stops[stoppedAtMaximum] + s.stops[traceExhausted]
+	fmt.Printf("  Searches: %d stopped between iterations, %d at the maximum time, %d outlasted their trace\n",
+		s.stops[stoppedEarly], s.stops[stoppedAtMaximum], s.stops[traceExhausted])
+	if s.stops[traceExhausted] > moves/10 {
+		fmt.Println("  Warning: many traces are too short for this time control, so time use is understated")
+	}
+
+	if len(s.timeLeft) > 0 {
+		least, most, sum := s.timeLeft[0], s.timeLeft[0], time.Duration(0)
+		for _, left := range s.timeLeft {
+			least = min(least, left)
+			most = max(most, left)
+			sum += left
+		}
+		fmt.Printf("  Time left at the end, on the lower clock: average %s, least %s, most %s\n",
+			(sum / time.Duration(len(s.timeLeft))).Round(time.Millisecond), least.Round(time.Millisecond), most.Round(time.Millisecond))
+	}
+
+	fmt.Printf("  Flag falls: %d of %d games\n", len(s.flagFalls), s.games)
+	for _, flagFall := range s.flagFalls {
+		fmt.Printf("    %s\n", flagFall)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Two games, interleaved, and a blank line: a game of four plies in the opening, and a king and pawn
+// endgame in which black's second search outlasts its clock
+const traceFile = "testdata/traces.ndjson"
+
+func TestReadTraces(t *testing.T) {
+	games, err := readTraces(traceFile)
+	if err != nil {
+		t.Fatalf("readTraces: %s", err)
+	}
+
+	var plies [][]int
+	for _, moves := range games {
+		var gamePlies []int
+		for _, trace := range moves {
+			gamePlies = append(gamePlies, trace.Ply)
+		}
+		plies = append(plies, gamePlies)
+	}
+	if want := [][]int{{0, 1, 2, 3}, {0, 1}}; !reflect.DeepEqual(plies, want) {
+		t.Errorf("Read plies %v, want %v", plies, want)
+	}
+
+	if elapsed := games[0][0].Iterations[1].elapsed(); elapsed != 9*time.Second {
+		t.Errorf("Second iteration ended after %s, want 9s", elapsed)
+	}
+
+	// Plies must follow each other, and every trace needs an iteration
+	malformed := []string{
+		`{"game":1,"ply":0,"fen":"8/8/4k3/8/8/4K3/4P3/8 w - - 0 1","iterations":[{"depth":1,"timeMs":1,"bestMove":"e3d4"}]}` + "\n" +
+			`{"game":1,"ply":2,"fen":"8/8/4k3/8/3K4/8/4P3/8 b - - 1 1","iterations":[{"depth":1,"timeMs":1,"bestMove":"e6d6"}]}`,
+		`{"game":1,"ply":0,"fen":"8/8/4k3/8/8/4K3/4P3/8 w - - 0 1","iterations":[]}`,
+		`{"game":1,"ply":0`,
+	}
+	for _, text := range malformed {
+		filename := filepath.Join(t.TempDir(), "traces.ndjson")
+		os.WriteFile(filename, []byte(text), 0o644)
+		if _, err := readTraces(filename); err == nil {
+			t.Errorf("readTraces %s without error", text)
+		}
+	}
+}
+
+func TestSimulation(t *testing.T) {
+	games, err := readTraces(traceFile)
+	if err != nil {
+		t.Fatalf("readTraces: %s", err)
+	}
+
+	tc, err := parseTimeControl("2/10")
+	if err != nil {
+		t.Fatalf("parseTimeControl: %s", err)
+	}
+
+	s := &simulation{tc: tc, latency: 100 * time.Millisecond}
+	for _, moves := range games {
+		if err := s.playGame(moves); err != nil {
+			t.Fatalf("playGame: %s", err)
+		}
+	}
+
+	// With 2 moves to play in 10s and no move overhead, the optimum is the time left over the moves
+	// to go and the maximum three quarters of the time left.
+	//
+	// Game 1:
+	//   white 10s, 2 to go: optimum 5s, maximum 7.5s. Depth 2 ends at 9s, so stops at 7.5s, leaving 2.4s.
+	//   black 10s, 2 to go: depth 1 ends at 3s, over half of 5s, so stops, leaving 6.9s.
+	//   white 2.4s, 1 to go: maximum 1.8s. Depth 1 ends at 0.5s and the trace with it, leaving 1.8s + 10s.
+	//   black 6.9s, 1 to go: maximum 5.175s. Depth 2 ends at 6s, so stops at 5.175s, leaving 1.625s + 10s.
+	// Game 2:
+	//   white 10s: the trace ends at 2s, under half the optimum, leaving 7.9s.
+	//   black 10s: depth 1 always completes, at 11s, and the flag falls 1.1s over.
+	wantPhaseTime := [3]time.Duration{16175 * time.Millisecond, 0, 13 * time.Second}
+	if s.phaseTime != wantPhaseTime || s.phaseMoves != [3]int{4, 0, 2} {
+		t.Errorf("Phase times %v over %v moves, want %v over [4 0 2]", s.phaseTime, s.phaseMoves, wantPhaseTime)
+	}
+	if want := [stopReasons]int{2, 2, 2}; s.stops != want {
+		t.Errorf("Stops %v, want %v", s.stops, want)
+	}
+	if want := []time.Duration{11625 * time.Millisecond, -1100 * time.Millisecond}; !reflect.DeepEqual(s.timeLeft, want) {
+		t.Errorf("Time left %v, want %v", s.timeLeft, want)
+	}
+	if want := []string{"game 2 ply 1, 1.1s over"}; !reflect.DeepEqual(s.flagFalls, want) {
+		t.Errorf("Flag falls %q, want %q", s.flagFalls, want)
+	}
+
+	report := captureOutput(t, s.report)
+	for _, line := range []string{
+		"Time control 2/10, move overhead 0s, latency 100ms",
+		"    opening        4 moves     16.175s total      4.044s per move   55.4%",
+		"    middlegame     0 moves          0s total          0s per move    0.0%",
+		"    endgame        2 moves         13s total        6.5s per move   44.6%",
+		"  Searches: 2 stopped between iterations, 2 at the maximum time, 2 outlasted their trace",
+		"  Warning: many traces are too short for this time control",
+		"  Time left at the end, on the lower clock: average 5.263s, least -1.1s, most 11.625s",
+		"  Flag falls: 1 of 2 games",
+		"    game 2 ply 1, 1.1s over",
+	} {
+		if !strings.Contains(report, line) {
+			t.Errorf("Report lacks '%s':\n%s", line, report)
+		}
+	}
+}
+
+// captureOutput returns what the function prints
+func captureOutput(t *testing.T, print func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %s", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	captured := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		captured <- string(data)
+	}()
+
+	print()
+	writer.Close()
+
+	return <-captured
+}
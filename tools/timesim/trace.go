@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+	"goche/utility"
+)
+
+// moveTrace is the record of the search for one move of a game: how long each iteration took to
+// complete, and what it found
+type moveTrace struct {
+	Game       int         `json:"game"`
+	Ply        int         `json:"ply"`
+	Fen        string      `json:"fen"`
+	Iterations []iteration `json:"iterations"`
+}
+
+type iteration struct {
+	Depth    int     `json:"depth"`
+	TimeMs   float64 `json:"timeMs"`
+	BestMove string  `json:"bestMove"`
+	Score    int     `json:"score"`
+	Mate     bool    `json:"mate,omitempty"`
+}
+
+// elapsed returns the time from the start of the search to the end of the iteration
+func (i iteration) elapsed() time.Duration {
+	return time.Duration(i.TimeMs * float64(time.Millisecond))
+}
+
+// score returns the iteration's score as the engine reported it
+func (i iteration) score() utility.Score {
+	return utility.Score{Value: i.Score, Mate: i.Mate}
+}
+
+// readTraces reads a file of move traces, one JSON object per line, and groups them into games in
+// the order of their plies
+func readTraces(filename string) ([][]moveTrace, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var games [][]moveTrace
+	index := make(map[int]int)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var trace moveTrace
+		if err := json.Unmarshal(scanner.Bytes(), &trace); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(trace.Iterations) == 0 {
+			return nil, fmt.Errorf("line %d: no iterations", line)
+		}
+
+		gameIndex, found := index[trace.Game]
+		if !found {
+			gameIndex = len(games)
+			index[trace.Game] = gameIndex
+			games = append(games, nil)
+		}
+
+		moves := games[gameIndex]
+		if len(moves) > 0 && trace.Ply != moves[len(moves)-1].Ply+1 {
+			return nil, fmt.Errorf("line %d: game %d ply %d does not follow ply %d", line, trace.Game, trace.Ply, moves[len(moves)-1].Ply)
+		}
+		games[gameIndex] = append(moves, trace)
+	}
+
+	return games, scanner.Err()
+}
+
+// recordTraces searches every position of each game in the file, which holds one game per line in the
+// form of the arguments to a UCI 'position' command, and writes a trace of each search
+func recordTraces(gamesFile string, output io.Writer, limits engine.Limits) error {
+	data, err := os.ReadFile(gamesFile)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(output)
+	defer writer.Flush()
+
+	e := engine.New()
+	encoder := json.NewEncoder(writer)
+
+	game := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		game++
+
+		positions, err := gamePositions(line)
+		if err != nil {
+			return fmt.Errorf("game %d: %w", game, err)
+		}
+
+		e.NewGame()
+
+		for ply, board := range positions {
+			fmt.Fprintf(os.Stderr, "Recording game %d ply %d\n", game, ply)
+
+			trace := moveTrace{Game: game, Ply: ply, Fen: board.ToFen()}
+
+			reporter := engine.NewChannelReporter(256)
+			e.SetPosition(board)
+			e.Go(limits, reporter)
+
+			for searching := true; searching; {
+				select {
+				case progress := <-reporter.Progress:
+					trace.Iterations = append(trace.Iterations, newIteration(progress))
+				case <-reporter.Result:
+					searching = false
+				}
+			}
+
+			// Progress sent just before the result may still be waiting
+			for len(reporter.Progress) > 0 {
+				trace.Iterations = append(trace.Iterations, newIteration(<-reporter.Progress))
+			}
+
+			if len(trace.Iterations) == 0 {
+				continue
+			}
+
+			if err := encoder.Encode(trace); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func newIteration(progress engine.Progress) iteration {
+	bestMove := ""
+	if len(progress.PV) > 0 {
+		bestMove = progress.PV[0]
+	}
+
+	return iteration{
+		Depth:    progress.Depth,
+		TimeMs:   float64(progress.Elapsed) / float64(time.Millisecond),
+		BestMove: bestMove,
+		Score:    progress.Score.Value,
+		Mate:     progress.Score.Mate,
+	}
+}
+
+// gamePositions returns the positions before each move of a game, given as 'startpos moves ...' or
+// 'fen <fen> moves ...', leaving out the final position if the game is over
+func gamePositions(text string) ([]*chess.Board, error) {
+	arguments := utility.ParseArguments(utility.Tokenize(text), "startpos", "fen", "moves")
+
+	fen := chess.FenStartingPosition
+	if arguments.Has("fen") {
+		fen = arguments.Value("fen")
+	}
+
+	board, err := chess.NewBoard(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := []*chess.Board{}
+	for _, text := range arguments.Values("moves") {
+		position := *board
+		positions = append(positions, &position)
+
+		move, err := board.FindMove(text)
+		if err != nil {
+			return nil, err
+		}
+		board.MakeMove(move)
+	}
+
+	if board.Status() == chess.InProgress {
+		positions = append(positions, board)
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,334 @@
+package transcript
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	// Internal references
+	"goche/utility"
+)
+
+// Direction marks whether a line was received by the engine or sent by it
+type Direction string
+
+const (
+	Incoming Direction = ">"
+	Outgoing Direction = "<"
+)
+
+// The timestamp format, which keeps microseconds so that replay can reproduce the timing closely
+const timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Entry is a single line of a transcript
+type Entry struct {
+	Time      time.Time
+	Direction Direction
+	Line      string
+}
+
+// Recorder writes a transcript of a UCI session, one line per entry in the form:
+//
+//	<timestamp> <direction> <line>
+//
+// where the direction is '>' for a line received by the engine and '<' for a line it sent
+type Recorder struct {
+	mutex  sync.Mutex
+	output *bufio.Writer
+}
+
+// NewRecorder creates a Recorder writing to the given destination
+func NewRecorder(output io.Writer) *Recorder {
+	return &Recorder{
+		output: bufio.NewWriter(output),
+	}
+}
+
+// Record adds a line to the transcript. Each entry is flushed immediately, so that the transcript
+// is complete even if the engine is killed by the GUI.
+func (r *Recorder) Record(direction Direction, line string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fmt.Fprintf(r.output, "%s %s %s\n", time.Now().Format(timeFormat), direction, line)
+	r.output.Flush()
+}
+
+// Read parses a transcript
+func Read(input io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(input)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// The line itself may be empty or contain spaces, so only split off the first two fields
+		fields := strings.SplitN(text, " ", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed transcript at line %d", lineNumber)
+		}
+
+		timestamp, err := time.Parse(timeFormat, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed timestamp at line %d: %w", lineNumber, err)
+		}
+
+		direction := Direction(fields[1])
+		if direction != Incoming && direction != Outgoing {
+			return nil, fmt.Errorf("unknown direction '%s' at line %d", fields[1], lineNumber)
+		}
+
+		entry := Entry{Time: timestamp, Direction: direction}
+		if len(fields) == 3 {
+			entry.Line = fields[2]
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading transcript: %w", err)
+	}
+
+	return entries, nil
+}
+
+// How long replay waits for the engine to catch up with the output recorded before an incoming line
+const catchUpTimeout = 2 * time.Second
+
+// Replay passes the incoming lines of a transcript to the engine with their original timing,
+// relative to the start of the transcript. A GUI usually waits for a response, such as 'bestmove',
+// before sending its next command, so each line is also held back until the engine has written as
+// much output as had been recorded before it. It returns false if the engine was told to quit.
+func Replay(entries []Entry, process func(input string) bool, capture *Capture) bool {
+	if len(entries) == 0 {
+		return true
+	}
+
+	recordedStart := entries[0].Time
+	start := time.Now()
+	recordedOutput := 0
+
+	for _, entry := range entries {
+		if entry.Direction != Incoming {
+			recordedOutput++
+			continue
+		}
+
+		wait := entry.Time.Sub(recordedStart) - time.Since(start)
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+
+		capture.waitFor(recordedOutput, catchUpTimeout)
+
+		if !process(entry.Line) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Lines returns the lines of the transcript in the given direction
+func Lines(entries []Entry, direction Direction) []string {
+	var lines []string
+	for _, entry := range entries {
+		if entry.Direction == direction {
+			lines = append(lines, entry.Line)
+		}
+	}
+	return lines
+}
+
+// Capture collects the engine output during a replay, for comparison with the transcript
+type Capture struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+// NewCapture creates a Capture of everything written to the output
+func NewCapture(output *utility.Writer) *Capture {
+	c := &Capture{}
+	output.AddTap(func(line string) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		c.lines = append(c.lines, line)
+	})
+	return c
+}
+
+// Lines returns the output captured so far
+func (c *Capture) Lines() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]string(nil), c.lines...)
+}
+
+// waitFor waits until at least the given number of lines have been captured, or the timeout expires
+func (c *Capture) waitFor(count int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for len(c.Lines()) < count && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Diff compares the recorded output with the replayed output, returning the differences in the
+// style of a unified diff: lines prefixed with '-' were recorded but not replayed, and lines
+// prefixed with '+' were replayed but not recorded. Timing values, which are expected to differ
+// from one run to the next, are ignored. It returns nil if there are no differences.
+//
+// Sessions may run to tens of thousands of lines, so the differences are found with Myers'
+// linear space algorithm, which takes time in proportion to the length of the session times the
+// number of differences rather than to the product of the lengths.
+func Diff(recorded []string, replayed []string) []string {
+	d := &differ{
+		a:        normalizeAll(recorded),
+		b:        normalizeAll(replayed),
+		recorded: recorded,
+		replayed: replayed,
+	}
+	d.compare(0, len(recorded), 0, len(replayed))
+
+	return d.differences
+}
+
+// differ holds the lines being compared and the differences found so far
+type differ struct {
+	a, b               []string
+	recorded, replayed []string
+	differences        []string
+}
+
+// compare adds the differences between a[aStart:aEnd] and b[bStart:bEnd], dividing the problem at
+// the middle of a shortest edit until one side or the other is empty
+func (d *differ) compare(aStart, aEnd, bStart, bEnd int) {
+	// Lines in common at either end are not part of any edit
+	for aStart < aEnd && bStart < bEnd && d.a[aStart] == d.b[bStart] {
+		aStart++
+		bStart++
+	}
+	for aStart < aEnd && bStart < bEnd && d.a[aEnd-1] == d.b[bEnd-1] {
+		aEnd--
+		bEnd--
+	}
+
+	switch {
+	case aStart == aEnd:
+		for j := bStart; j < bEnd; j++ {
+			d.differences = append(d.differences, "+"+d.replayed[j])
+		}
+
+	case bStart == bEnd:
+		for i := aStart; i < aEnd; i++ {
+			d.differences = append(d.differences, "-"+d.recorded[i])
+		}
+
+	default:
+		x, y, u, v := middleSnake(d.a[aStart:aEnd], d.b[bStart:bEnd])
+		d.compare(aStart, aStart+x, bStart, bStart+y)
+		d.compare(aStart+u, aEnd, bStart+v, bEnd)
+	}
+}
+
+// middleSnake finds the run of matching lines, from (x, y) to (u, v), in the middle of a shortest
+// edit of a into b. The edit is searched for from both ends at once until the two searches meet.
+func middleSnake(a []string, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+
+	// The furthest reaching x on each diagonal k = x - y, going forward from the start and, in
+	// reversed coordinates, backward from the end
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for edits := 0; edits <= limit; edits++ {
+		for k := -edits; k <= edits; k += 2 {
+			var x int
+			if k == -edits || (k != edits && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			if reverse := delta - k; odd && reverse >= -(edits-1) && reverse <= edits-1 && x+backward[offset+reverse] >= n {
+				return startX, startY, x, y
+			}
+		}
+
+		for k := -edits; k <= edits; k += 2 {
+			var x int
+			if k == -edits || (k != edits && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+
+			startX, startY := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			if reverse := delta - k; !odd && reverse >= -edits && reverse <= edits && forward[offset+reverse]+x >= n {
+				return n - x, m - y, n - startX, m - startY
+			}
+		}
+	}
+
+	// The searches always meet by the time half of the longest possible edit has been made
+	panic("transcript: no middle snake")
+}
+
+// normalizeAll masks the values that vary between runs in each line of output
+func normalizeAll(lines []string) []string {
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = normalize(line)
+	}
+	return normalized
+}
+
+// normalize masks the time and speed reported in an 'info' line
+func normalize(line string) string {
+	tokens := utility.Tokenize(line)
+	if len(tokens) == 0 || tokens[0] != "info" {
+		return line
+	}
+
+	for i := 1; i < len(tokens)-1; i++ {
+		switch tokens[i] {
+		case "string":
+			// The rest of the line is free text
+			return strings.Join(tokens, " ")
+		case "time", "nps":
+			tokens[i+1] = "*"
+		}
+	}
+
+	return strings.Join(tokens, " ")
+}
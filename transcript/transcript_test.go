@@ -0,0 +1,230 @@
+package transcript
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	// Internal references
+	"goche/utility"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	recorder := NewRecorder(buffer)
+
+	recorded := []Entry{
+		{Direction: Incoming, Line: "position startpos moves e2e4"},
+		{Direction: Outgoing, Line: ""},
+		{Direction: Outgoing, Line: "info string  two  spaces"},
+		{Direction: Incoming, Line: "quit"},
+	}
+	before := time.Now()
+	for _, entry := range recorded {
+		recorder.Record(entry.Direction, entry.Line)
+	}
+
+	entries, err := Read(buffer)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if len(entries) != len(recorded) {
+		t.Fatalf("Read %d entries, want %d", len(entries), len(recorded))
+	}
+
+	for i, entry := range entries {
+		if entry.Direction != recorded[i].Direction || entry.Line != recorded[i].Line {
+			t.Errorf("Entry %d is %s '%s', want %s '%s'", i, entry.Direction, entry.Line, recorded[i].Direction, recorded[i].Line)
+		}
+		if entry.Time.Before(before.Truncate(time.Microsecond)) || entry.Time.After(time.Now()) {
+			t.Errorf("Entry %d recorded at %s, outside the recording", i, entry.Time)
+		}
+	}
+
+	if lines := Lines(entries, Incoming); !reflect.DeepEqual(lines, []string{"position startpos moves e2e4", "quit"}) {
+		t.Errorf("Incoming lines %q", lines)
+	}
+}
+
+func TestReadRejectsMalformedTranscripts(t *testing.T) {
+	malformed := []string{
+		"2024-01-02T03:04:05.000000Z",
+		"yesterday > uci",
+		"2024-01-02T03:04:05.000000Z = uci",
+	}
+
+	for _, text := range malformed {
+		if _, err := Read(strings.NewReader(text)); err == nil {
+			t.Errorf("Read '%s' without error", text)
+		}
+	}
+
+	// Blank lines are skipped
+	entries, err := Read(strings.NewReader("\n2024-01-02T03:04:05.000000Z > uci\n\n"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Read %d entries with error %v, want 1", len(entries), err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	at := func(milliseconds int) time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Add(time.Duration(milliseconds) * time.Millisecond)
+	}
+	entries := []Entry{
+		{at(0), Incoming, "go"},
+		{at(10), Outgoing, "bestmove e2e4"},
+		{at(20), Incoming, "isready"},
+		{at(30), Outgoing, "readyok"},
+		{at(200), Incoming, "quit"},
+		{at(300), Incoming, "never sent"},
+	}
+
+	output := utility.NewWriter(io.Discard)
+	capture := NewCapture(output)
+
+	// The engine answers 'go' late, and replay must wait for the answer before sending 'isready'
+	start := time.Now()
+	var processed []string
+	process := func(input string) bool {
+		processed = append(processed, fmt.Sprintf("%s after %d lines", input, len(capture.Lines())))
+		switch input {
+		case "go":
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				output.WriteLine("bestmove d2d4")
+			}()
+		case "isready":
+			output.WriteLine("readyok")
+		}
+		return input != "quit"
+	}
+
+	if Replay(entries, process, capture) {
+		t.Error("Replay continued after 'quit'")
+	}
+
+	want := []string{"go after 0 lines", "isready after 1 lines", "quit after 2 lines"}
+	if !reflect.DeepEqual(processed, want) {
+		t.Errorf("Processed %q, want %q", processed, want)
+	}
+
+	// The recorded timing is kept
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Replayed in %s, recorded over 200ms", elapsed)
+	}
+
+	differences := Diff(Lines(entries, Outgoing), capture.Lines())
+	if !reflect.DeepEqual(differences, []string{"-bestmove e2e4", "+bestmove d2d4"}) {
+		t.Errorf("Differences %q", differences)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		recorded    []string
+		replayed    []string
+		differences []string
+	}{
+		{nil, nil, nil},
+		{[]string{"uciok", "readyok"}, []string{"uciok", "readyok"}, nil},
+		{
+			[]string{"info depth 1 time 3 nps 1000 pv e2e4"},
+			[]string{"info depth 1 time 5 nps 2000 pv e2e4"},
+			nil,
+		},
+		{
+			[]string{"info string time 3"},
+			[]string{"info string time 5"},
+			[]string{"-info string time 3", "+info string time 5"},
+		},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}, []string{"-b"}},
+		{[]string{"a", "c"}, []string{"a", "b", "c"}, []string{"+b"}},
+		{[]string{"a", "b", "c"}, nil, []string{"-a", "-b", "-c"}},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{"-b", "+x"}},
+		{
+			[]string{"a", "b", "c", "d", "e"},
+			[]string{"b", "c", "x", "e", "f"},
+			[]string{"-a", "-d", "+x", "+f"},
+		},
+	}
+
+	for _, test := range tests {
+		if differences := Diff(test.recorded, test.replayed); !reflect.DeepEqual(differences, test.differences) {
+			t.Errorf("Diff(%q, %q) = %q, want %q", test.recorded, test.replayed, differences, test.differences)
+		}
+	}
+}
+
+// longestCommon is the length of the longest common subsequence, the size of which a shortest edit
+// leaves unchanged
+func longestCommon(a []string, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+func TestDiffIsShortestEdit(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, random.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + random.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		recorded, replayed := randomLines(), randomLines()
+		differences := Diff(recorded, replayed)
+
+		// A shortest edit removes every recorded line, and adds every replayed line, outside a longest
+		// common subsequence
+		removed := 0
+		for _, difference := range differences {
+			if difference[0] == '-' {
+				removed++
+			}
+		}
+
+		common := longestCommon(recorded, replayed)
+		if removed != len(recorded)-common || len(differences)-removed != len(replayed)-common {
+			t.Fatalf("Diff(%q, %q) = %q, keeping %d lines in common", recorded, replayed, differences, common)
+		}
+	}
+}
+
+func TestDiffLongSession(t *testing.T) {
+	// A table of every recorded line against every replayed one would take gigabytes
+	const length = 100000
+
+	recorded := make([]string, length)
+	replayed := make([]string, length)
+	for i := range recorded {
+		recorded[i] = fmt.Sprintf("info depth %d time %d", i, i)
+		replayed[i] = fmt.Sprintf("info depth %d time %d", i, 2*i)
+	}
+	replayed[10] = "bestmove e2e4"
+	replayed[length/2] = "bestmove d2d4"
+
+	differences := Diff(recorded, replayed)
+	want := []string{"-info depth 10 time 10", "+bestmove e2e4", "-" + recorded[length/2], "+bestmove d2d4"}
+	if !reflect.DeepEqual(differences, want) {
+		t.Errorf("Differences %q, want %q", differences, want)
+	}
+}
@@ -0,0 +1,105 @@
+package uci
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+	"goche/logger"
+	"goche/utility"
+)
+
+// Process 'd' - display the current position, optionally with Unicode pieces and the bitboards
+func dCommand(configuration *configuration, arguments []string) bool {
+	parsed := utility.ParseArguments(arguments, "ascii", "unicode", "masks")
+	reportUnknownArguments(configuration, "d", parsed)
+
+	position := configuration.engine.Position()
+
+	for _, line := range position.Draw(parsed.Has("unicode")) {
+		configuration.output.WriteLine("%s", line)
+	}
+
+	if parsed.Has("masks") {
+		configuration.output.WriteLine("")
+		for _, line := range position.DrawMasks() {
+			configuration.output.WriteLine("%s", line)
+		}
+	}
+
+	return true
+}
+
+// Process 'moves' - list the legal moves in the current position in UCI and SAN notation
+func movesCommand(configuration *configuration, _ []string) bool {
+	position := configuration.engine.Position()
+
+	moveList, err := position.GetMoves(make([]chess.Move, 0, 256))
+	if err != nil {
+		logger.Error("Move generation failed: %s", err)
+		return true
+	}
+
+	// Sort for a stable listing that is easy to compare
+	sort.Slice(moveList, func(i, j int) bool {
+		return moveList[i].ToUciString() < moveList[j].ToUciString()
+	})
+
+	uciMoves := make([]string, len(moveList))
+	sanMoves := make([]string, len(moveList))
+	for i, move := range moveList {
+		uciMoves[i] = move.ToUciString()
+		sanMoves[i] = position.ToSan(move)
+	}
+
+	configuration.output.WriteLine("Legal moves: %d", len(moveList))
+	configuration.output.WriteLine("UCI: %s", strings.Join(uciMoves, " "))
+	configuration.output.WriteLine("SAN: %s", strings.Join(sanMoves, " "))
+
+	return true
+}
+
+// Process 'fen' - write the current position as FEN
+func fenCommand(configuration *configuration, _ []string) bool {
+	configuration.output.WriteLine("%s", configuration.engine.Position().ToFen())
+	return true
+}
+
+// Process 'flip' - mirror the current position, swapping the colors
+func flipCommand(configuration *configuration, _ []string) bool {
+	position := configuration.engine.Position().Mirror()
+	configuration.engine.SetPosition(position)
+
+	logger.Debug("Position flipped to %s", position.ToFen())
+	return true
+}
+
+// Process 'eval' - show the static evaluation of the current position, term by term
+func evalCommand(configuration *configuration, _ []string) bool {
+	position := configuration.engine.Position()
+	breakdown := engine.EvaluateTerms(position)
+
+	configuration.output.WriteLine("%-16s %7s %7s %7s", "Term", "White", "Black", "Total")
+	configuration.output.WriteLine("%s", strings.Repeat("-", 40))
+
+	for _, term := range breakdown.Terms {
+		configuration.output.WriteLine("%-16s %7d %7d %7s", term.Name, term.White, term.Black, formatScore(term.White-term.Black))
+	}
+
+	configuration.output.WriteLine("%s", strings.Repeat("-", 40))
+	configuration.output.WriteLine("%-16s %7s %7s %7s", "Total", "", "", formatScore(breakdown.Total))
+	configuration.output.WriteLine("")
+	configuration.output.WriteLine("Game phase: %d of %d, where 0 is the endgame", breakdown.Phase, breakdown.MaxPhase)
+	configuration.output.WriteLine("Evaluation: %s centipawns from white's point of view, %s for the side to move",
+		formatScore(breakdown.Total), formatScore(engine.Evaluate(position)))
+
+	return true
+}
+
+// formatScore writes a score in centipawns with an explicit sign
+func formatScore(centipawns int) string {
+	return fmt.Sprintf("%+d", centipawns)
+}
@@ -4,6 +4,7 @@ import (
 	// Internal references
 	"bufio"
 	"fmt"
+	"goche/chess"
 	"goche/logger"
 	"goche/utility"
 	"os"
@@ -13,36 +14,36 @@ import (
 )
 
 // Perform a search to a depth with a FEN string and display the results
-func PerftDepth(depth int, fen string, divide bool) error {
+func PerftDepth(output *utility.Writer, depth int, fen string, divide bool) error {
 	if fen == "" {
 		return fmt.Errorf("missing FEN string")
 	}
 
 	logger.Debug("perft to depth %d with FEN: %s", depth, fen)
 
-	result, err := perftRun(depth, fen, divide)
+	result, err := perftRun(output, depth, fen, divide)
 	if err != nil {
 		return fmt.Errorf("run failed: %w", err)
 	}
 
-	fmt.Printf("  Depth: %3d. Actual: %12d\n", depth, result)
+	output.WriteLine("  Depth: %3d. Actual: %12d", depth, result)
 
 	return nil
 }
 
 // Process a FEN string that contains expected results (error if not)
-func PerftWithFen(fen string, divide bool) error {
+func PerftWithFen(output *utility.Writer, fen string, divide bool) error {
 	if fen == "" {
 		return fmt.Errorf("missing FEN string")
 	}
 
 	logger.Debug("perft with FEN: %s", fen)
 
-	return perftFen(fen, divide)
+	return perftFen(output, fen, divide)
 }
 
 // Read and process a file of FEN strings, each of which contain expected results (error if not)
-func PerftWithFile(filename string, divide bool) error {
+func PerftWithFile(output *utility.Writer, filename string, divide bool) error {
 	if filename == "" {
 		return fmt.Errorf("missing filename")
 	}
@@ -64,11 +65,11 @@ func PerftWithFile(filename string, divide bool) error {
 
 		// Skip empty lines and comment lines - but print them as they might be in the file for formatting purposes
 		if line == "" || strings.HasPrefix(line, "#") {
-			fmt.Println(line)
+			output.WriteLine("%s", line)
 			continue
 		}
 
-		perftFen(line, divide)
+		perftFen(output, line, divide)
 	}
 
 	// Check for any errors during scanning
@@ -79,7 +80,7 @@ func PerftWithFile(filename string, divide bool) error {
 	return nil
 }
 
-func perftFen(fenWithResults string, divide bool) error {
+func perftFen(output *utility.Writer, fenWithResults string, divide bool) error {
 
 	// FEN format is expected to be one of:
 	// - fen;Ddepth expected-at-depth;Ddepth expected-at-depth
@@ -127,19 +128,19 @@ func perftFen(fenWithResults string, divide bool) error {
 	}
 
 	// Now run the actual test
-	fmt.Printf("FEN: %s\n", fen)
+	output.WriteLine("FEN: %s", fen)
 	for i := 0; i < len(expected); i++ {
 		depth := expected[i].depth
 		count := expected[i].moveCount
 
 		logger.Debug("perft to depth %d with FEN: %s", depth, fen)
 
-		result, err := perftRun(depth, fen, divide)
+		result, err := perftRun(output, depth, fen, divide)
 		if err != nil {
 			return fmt.Errorf("run failed: %w", err)
 		}
 
-		fmt.Printf("  Depth: %3d. Expected: %12d. Actual: %12d. %s\n", depth, count, result, utility.If(count == result, "PASSED", "FAILED"))
+		output.WriteLine("  Depth: %3d. Expected: %12d. Actual: %12d. %s", depth, count, result, utility.If(count == result, "PASSED", "FAILED"))
 	}
 
 	return nil
@@ -169,8 +170,8 @@ func getDepthAndExpected(expected string) (int, int, error) {
 	return depth, count, nil
 }
 
-func perftRun(depth int, fen string, divide bool) (int, error) {
-	board, err := NewBoard(fen)
+func perftRun(output *utility.Writer, depth int, fen string, divide bool) (int, error) {
+	board, err := chess.NewBoard(fen)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create board: %w", err)
 	}
@@ -178,29 +179,28 @@ func perftRun(depth int, fen string, divide bool) (int, error) {
 	start := time.Now()
 
 	var nodes int
-	nodes, err = search(board, depth, divide)
+	nodes, err = search(output, board, depth, divide)
 
 	if err != nil {
 		return 0, fmt.Errorf("move search failed: %w", err)
 	}
 
 	elapsed := time.Since(start)
-	fmt.Printf("  Depth: %3d. Nodes: %3d. Time: %s\n", depth, nodes, elapsed)
+	output.WriteLine("  Depth: %3d. Nodes: %3d. Time: %s", depth, nodes, elapsed)
 
 	return nodes, nil
 }
 
-func search(board *Board, depth int, divide bool) (int, error) {
-	nodes := 0
-
-	// Always return 1 at the root - but I guess this could/should actually be
-	// a check of a pseudo-level moves if our engine return such things?
-	if depth == 0 {
-		return 1, nil
+// search counts the positions reached at the given depth, writing the count after each move if dividing
+func search(output *utility.Writer, board *chess.Board, depth int, divide bool) (int, error) {
+	if !divide || depth == 0 {
+		return chess.Perft(board, depth)
 	}
 
+	nodes := 0
+
 	// Create an array for possible moves and allocate it to the maximum size necessary
-	moveList := make([]Move, 0, 256)
+	moveList := make([]chess.Move, 0, 256)
 
 	// Generate all possible moves
 	moveList, err := board.GetMoves(moveList)
@@ -208,25 +208,20 @@ func search(board *Board, depth int, divide bool) (int, error) {
 		return 0, fmt.Errorf("move generation failed: %w", err)
 	}
 
-	// Recurse with each move
+	// Count the positions after each move
 	for i := 0; i < len(moveList); i++ {
 		move := moveList[i]
 
-		// Make the move, search the new position, unmake the move - repeat
-
 		undo := board.MakeMove(move)
 
-		moveNodes, err := search(board, depth-1, false)
+		moveNodes, err := chess.Perft(board, depth-1)
 		if err != nil {
 			return 0, err
 		}
 
 		nodes += moveNodes
 
-		// Extra reporting if requested
-		if divide {
-			fmt.Printf("  %s : %d : %p\n", move.ToString(), moveNodes, board)
-		}
+		output.WriteLine("  %s : %d : %p", move.ToString(), moveNodes, board)
 
 		board.UnmakeMove(undo)
 	}
@@ -0,0 +1,142 @@
+package uci
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Internal references
+	"goche/engine"
+	"goche/logger"
+	"goche/utility"
+)
+
+// uciReporter writes search progress and results as UCI 'info' and 'bestmove'. In debug mode it also
+// reports how well moves were ordered.
+type uciReporter struct {
+	output *utility.Writer
+	debug  bool
+}
+
+func (r uciReporter) Thinking(progress engine.Progress) {
+	info := utility.NewInfo().Time(progress.Elapsed)
+	if len(progress.PV) > 0 {
+		info.Depth(progress.Depth).SelDepth(progress.SelDepth).Score(progress.Score).Nodes(progress.Nodes).HashFull(progress.HashFull).PV(progress.PV...)
+		if progress.Elapsed > 0 {
+			info.NPS(uint64(float64(progress.Nodes) / progress.Elapsed.Seconds()))
+		}
+		if progress.MultiPV > 0 {
+			info.MultiPV(progress.MultiPV)
+		}
+	}
+	r.output.WriteInfo(info)
+
+	// The counts are for the whole search, so they are reported once, with the first line
+	if r.debug && progress.Cutoffs > 0 && progress.MultiPV <= 1 {
+		rate := float64(progress.FirstMoveCutoffs) * 100 / float64(progress.Cutoffs)
+		r.output.WriteInfoString("Move ordering: %.1f%% of %d cutoffs on the first move", rate, progress.Cutoffs)
+	}
+}
+
+// SearchingMove writes the move being searched at the root, for GUIs to show while an iteration is
+// under way
+func (r uciReporter) SearchingMove(progress engine.Progress) {
+	info := utility.NewInfo().Depth(progress.Depth).CurrMove(progress.CurrMove, progress.CurrMoveNumber).
+		Nodes(progress.Nodes).Time(progress.Elapsed).HashFull(progress.HashFull)
+	if progress.Elapsed > 0 {
+		info.NPS(uint64(float64(progress.Nodes) / progress.Elapsed.Seconds()))
+	}
+	r.output.WriteInfo(info)
+}
+
+func (r uciReporter) BestMove(bestMove string, ponderMove string) {
+	r.output.WriteBestMove(bestMove, ponderMove)
+}
+
+// The keywords that may follow 'go'
+var goKeywords = []string{
+	"searchmoves", "ponder", "wtime", "btime", "winc", "binc", "movestogo", "depth", "nodes", "mate", "movetime", "infinite",
+}
+
+// parseSearchLimits reads the arguments of a 'go' command into the limits of a search
+func parseSearchLimits(arguments utility.Arguments) (engine.Limits, error) {
+	limits := engine.Limits{}
+
+	for _, keyword := range arguments.Keywords() {
+		values := arguments.Values(keyword)
+
+		switch keyword {
+		case "ponder", "infinite":
+			if keyword == "ponder" {
+				limits.Ponder = true
+			} else {
+				limits.Infinite = true
+			}
+
+			if len(values) > 0 {
+				logger.Warn("Skipped unexpected token(s) after '%s': %s", keyword, strings.Join(values, " "))
+			}
+
+		case "searchmoves":
+			for _, value := range values {
+				if !isMoveString(value) {
+					return limits, fmt.Errorf("bad move '%s' for searchmoves", value)
+				}
+				limits.SearchMoves = append(limits.SearchMoves, value)
+			}
+
+		default:
+			if len(values) == 0 {
+				return limits, fmt.Errorf("missing value for %s", keyword)
+			}
+			if len(values) > 1 {
+				logger.Warn("Skipped unexpected token(s) after '%s %s': %s", keyword, values[0], strings.Join(values[1:], " "))
+			}
+
+			number, err := strconv.Atoi(values[0])
+			if err != nil {
+				return limits, fmt.Errorf("bad value '%s' for %s", values[0], keyword)
+			}
+
+			duration := time.Duration(number) * time.Millisecond
+			switch keyword {
+			case "wtime", "btime", "winc", "binc", "movestogo":
+				limits.Clock = true
+			}
+
+			switch keyword {
+			case "wtime":
+				limits.WhiteTime = duration
+			case "btime":
+				limits.BlackTime = duration
+			case "winc":
+				limits.WhiteIncrement = duration
+			case "binc":
+				limits.BlackIncrement = duration
+			case "movetime":
+				limits.MoveTime = duration
+			case "movestogo":
+				limits.MovesToGo = number
+			case "depth":
+				limits.Depth = number
+			case "nodes":
+				limits.Nodes = number
+			case "mate":
+				limits.Mate = number
+			}
+		}
+	}
+
+	return limits, nil
+}
+
+// isMoveString reports whether the text looks like a move in long algebraic notation (e.g. e2e4, e7e8q)
+func isMoveString(text string) bool {
+	if len(text) != 4 && len(text) != 5 {
+		return false
+	}
+
+	return text[0] >= 'a' && text[0] <= 'h' && text[1] >= '1' && text[1] <= '8' &&
+		text[2] >= 'a' && text[2] <= 'h' && text[3] >= '1' && text[3] <= '8'
+}
@@ -4,14 +4,19 @@ import (
 
 	// Internal references
 	"fmt"
+	"goche/chess"
+	"goche/engine"
 	"goche/identification"
 	"goche/logger"
+	"goche/protection"
+	"goche/registration"
 	"goche/status"
 	"goche/utility"
 	"strconv"
+	"strings"
 )
 
-type Command func(*configuration, string) bool
+type Command func(*configuration, []string) bool
 
 var commands = map[string]Command{
 	// Core UCI commands
@@ -29,13 +34,23 @@ var commands = map[string]Command{
 
 	// Bespoke UCI commands
 	"perft": perftCommand,
+
+	// Diagnostic commands
+	"d":     dCommand,
+	"eval":  evalCommand,
+	"fen":   fenCommand,
+	"flip":  flipCommand,
+	"moves": movesCommand,
 }
 
 type configuration struct {
+	output               *utility.Writer
 	uciok                bool
 	debug                bool
 	registrationStatus   status.Status
+	registration         *registration.Registration
 	copyProtectionStatus status.Status
+	engine               *engine.Engine
 
 	// Transient
 	registrationWarningIssued bool
@@ -44,50 +59,72 @@ type configuration struct {
 // NewConfiguration creates a new configuration object with the debug flag set to false.
 //
 // Parameters:
-// - debug: whether to enable debug logging by default
+// - output: the writer through which all engine output is sent
 //
 // Returns a pointer to the newly created configuration object.
-func NewConfiguration() *configuration {
-	utility.WriteInfoString("Hello from %s version %s", identification.GetEngineName(), identification.GetVersionName())
-	return &configuration{
+func NewConfiguration(output *utility.Writer) *configuration {
+	output.WriteInfoString("Hello from %s version %s", identification.GetEngineName(), identification.GetVersionName())
+	configuration := &configuration{
+		output:                    output,
 		uciok:                     false,
 		debug:                     false,
 		registrationStatus:        status.Checking,
 		copyProtectionStatus:      status.Checking,
 		registrationWarningIssued: false,
 	}
+
+	configuration.engine = engine.New()
+
+	// Read any registration stored for this user, ready to be checked in response to 'uci'
+	stored, err := registration.Load()
+	if err != nil {
+		logger.Error("Unable to read the stored registration: %s", err)
+	}
+	configuration.registration = stored
+
+	return configuration
 }
 
 // ProcessCommand processes a UCI command by extracting the command and arguments
 // from the input string and executing the corresponding command function.
 //
+// As the UCI protocol requires, tokens may be separated by any whitespace and unknown
+// tokens ahead of a command are skipped, so that 'joho debug on' is processed as 'debug on'.
+//
 // Parameters:
 // - input: the input string containing the UCI command and arguments.
 //
 // Returns:
 // - bool: true if processing can continue with subsequent commands, false otherwise (e.g. to quit).
 func ProcessCommand(configuration *configuration, input string) bool {
-	command, arguments := utility.SplitNextWord(input)
+	tokens := utility.Tokenize(input)
 
-	if command == "" {
-		return true
+	// Skip unknown tokens until we find a command
+	skipped := 0
+	for skipped < len(tokens) && commands[tokens[skipped]] == nil {
+		skipped++
 	}
 
-	if commands[command] == nil {
+	if skipped > 0 {
 		// Illegal commands are reported and ignored
-		logger.Error("Unknown command '%s'", command)
+		logger.Error("Skipped unknown token(s) '%s'", strings.Join(tokens[:skipped], " "))
 
 		if configuration.debug {
-			utility.WriteInfoString("Unknown command '%s'", command)
+			configuration.output.WriteInfoString("Skipped unknown token(s) '%s'", strings.Join(tokens[:skipped], " "))
 		}
+	}
 
+	if skipped == len(tokens) {
 		return true
 	}
 
+	command := tokens[skipped]
+	arguments := tokens[skipped+1:]
+
 	logger.Debug("Received '%s' command", command)
 
 	if configuration.debug {
-		utility.WriteInfoString("Received '%s' command", command)
+		configuration.output.WriteInfoString("Received '%s' command", command)
 	}
 
 	// Check for registration
@@ -96,53 +133,66 @@ func ProcessCommand(configuration *configuration, input string) bool {
 			if configuration.uciok {
 				if command != "register" {
 					configuration.registrationWarningIssued = true
-					utility.WriteInfoString("The engine is not registered. Use 'register' to register your engine.")
+					configuration.output.WriteInfoString("The engine is not registered. Use 'register' to register your engine.")
 				}
 			}
 		}
 	}
 
-	// TODO refine this
-	if configuration.copyProtectionStatus == status.Error {
-		if configuration.uciok {
-			logger.Error("The engine copy protection status is not ok")
-			utility.WriteInfoString("The engine copy protection status is not ok")
-
-			// Cause the engine to shut down
-			// TODO is this the right thing to do?
-			return false
-		}
-	}
-
 	// Call the appropriate command handler
 	return commands[command](configuration, arguments)
 }
 
 // Process 'debug'
-func debugCommand(configuration *configuration, arguments string) bool {
+func debugCommand(configuration *configuration, arguments []string) bool {
+	parsed := utility.ParseArguments(arguments, "on", "off")
+	reportUnknownArguments(configuration, "debug", parsed)
+
+	if !parsed.Has("on") && !parsed.Has("off") {
+		logger.Error("Malformed debug command: expected 'on' or 'off'")
+		return true
+	}
+
 	// Note that this is for verbosity in sending info strings to the caller,
 	// not for our own logging, which is handled by the logger package
-	configuration.debug = arguments == "on"
+	keywords := parsed.Keywords()
+	configuration.debug = keywords[len(keywords)-1] == "on"
 
-	utility.WriteInfoString("Debug mode %s", utility.If(configuration.debug, "enabled", "disabled"))
+	configuration.output.WriteInfoString("Debug mode %s", utility.If(configuration.debug, "enabled", "disabled"))
 
 	return true
 }
 
-func goCommand(configuration *configuration, _ string) bool {
-	// TODO implement this
+// Process 'go'
+func goCommand(configuration *configuration, arguments []string) bool {
+	parsed := utility.ParseArguments(arguments, goKeywords...)
+	reportUnknownArguments(configuration, "go", parsed)
+
+	limits, err := parseSearchLimits(parsed)
+	if err != nil {
+		logger.Error("Malformed go command: %s", err)
+		return true
+	}
+
+	// The GUI should not start a search while one is running, but make sure we never have two
+	if configuration.engine.Searching() {
+		logger.Error("'go' received while a search is in progress")
+		configuration.engine.Stop(false)
+	}
+
+	configuration.engine.Go(limits, uciReporter{output: configuration.output, debug: configuration.debug})
 
 	return true
 }
 
-func isreadyCommand(configuration *configuration, _ string) bool {
-	// TODO check any ongoing activities and wait if necessary
-
-	utility.WriteReadyOk()
+// Process 'isready'
+func isreadyCommand(configuration *configuration, _ []string) bool {
+	// The search runs in its own goroutine, so we can answer straight away, even mid-search
+	configuration.output.WriteReadyOk()
 	return true
 }
 
-func perftCommand(configuration *configuration, arguments string) bool {
+func perftCommand(configuration *configuration, arguments []string) bool {
 	// TODO implement this
 
 	/*
@@ -152,9 +202,7 @@ func perftCommand(configuration *configuration, arguments string) bool {
 	   std::cout << "  perft file [filename] - perform searches read from a file as FEN strings with expected results" << std::endl;
 	*/
 
-	keyword, values := utility.SplitNextWord(arguments)
-
-	if keyword == "" {
+	if len(arguments) == 0 {
 		// TODO consider hijacking this to run a pre-determined set of perft tests
 		logger.Error("Missing perft command arguments")
 		return true
@@ -168,22 +216,30 @@ func perftCommand(configuration *configuration, arguments string) bool {
 	//
 	// Any of the above commands may begin with '-divide' to indicate that the search should be divided
 	divide := false
-	if keyword == "-divide" {
+	if arguments[0] == "-divide" {
 		divide = true
-		keyword, values = utility.SplitNextWord(values)
+		arguments = arguments[1:]
 	}
 
+	if len(arguments) == 0 {
+		logger.Error("Missing perft command arguments")
+		return true
+	}
+
+	keyword := arguments[0]
+	values := strings.Join(arguments[1:], " ")
+
 	depth, err := strconv.Atoi(keyword)
 	if err == nil {
 		if values == "" {
-			values = FenStartingPosition
+			values = chess.FenStartingPosition
 		}
 
-		err = PerftDepth(depth, values, divide)
+		err = PerftDepth(configuration.output, depth, values, divide)
 	} else if keyword == "fen" {
-		err = PerftWithFen(values, divide)
+		err = PerftWithFen(configuration.output, values, divide)
 	} else if keyword == "file" {
-		err = PerftWithFile(values, divide)
+		err = PerftWithFile(configuration.output, values, divide)
 	} else {
 		err = fmt.Errorf("unknown command: %s", keyword)
 	}
@@ -195,42 +251,97 @@ func perftCommand(configuration *configuration, arguments string) bool {
 	return true
 }
 
-func ponderhitCommand(configuration *configuration, _ string) bool {
-	// TODO implement this
+// Process 'ponderhit'
+func ponderhitCommand(configuration *configuration, _ []string) bool {
+	if !configuration.engine.PonderHit() {
+		logger.Warn("'ponderhit' received with no search in progress")
+	}
 
 	return true
 }
 
-func positionCommand(configuration *configuration, _ string) bool {
-	// TODO implement this
+// Process 'position'
+func positionCommand(configuration *configuration, arguments []string) bool {
+	parsed := utility.ParseArguments(arguments, "startpos", "fen", "moves")
+	reportUnknownArguments(configuration, "position", parsed)
+
+	var fen string
+	switch {
+	case parsed.Has("fen"):
+		fen = parsed.Value("fen")
+	case parsed.Has("startpos"):
+		fen = chess.FenStartingPosition
+	default:
+		logger.Error("Malformed position command: expected 'startpos' or 'fen'")
+		return true
+	}
+
+	board, err := chess.NewBoard(fen)
+	if err != nil {
+		logger.Error("Malformed position command: %s", err)
+		return true
+	}
+
+	// Play the moves, keeping the position reached if one of them is illegal
+	for _, text := range parsed.Values("moves") {
+		move, err := board.FindMove(text)
+		if err != nil {
+			logger.Error("Malformed position command: %s", err)
+
+			if configuration.debug {
+				configuration.output.WriteInfoString("Illegal move '%s' in position command", text)
+			}
+			break
+		}
+
+		board.MakeMove(move)
+	}
+
+	configuration.engine.SetPosition(board)
 
 	return true
 }
 
 // Process 'quit'
-func quitCommand(configuration *configuration, _ string) bool {
-	// TODO - check for, and terminate running threads etc, with no sending of bestmove
+func quitCommand(configuration *configuration, _ []string) bool {
+	Quit(configuration)
 
 	return false
 }
 
-func registerCommand(configuration *configuration, arguments string) bool {
-	// TODO create a registration object and do something useful with the name/code used here
+// Process 'register'
+func registerCommand(configuration *configuration, arguments []string) bool {
+	parsed := utility.ParseArguments(arguments, "later", "name", "code")
+	reportUnknownArguments(configuration, "register", parsed)
 
-	keyword, _ := utility.SplitNextWord(arguments)
-	switch keyword {
-	case "later":
-		configuration.registrationStatus = status.Ok
+	switch {
+	case parsed.Has("later"):
+		// The user has chosen not to register yet, so there is no need to remind them
+		logger.Debug("Registration deferred")
+		configuration.registrationWarningIssued = true
+		return true
+
+	case parsed.Value("name") != "" && parsed.Value("code") != "":
+		candidate := &registration.Registration{
+			Name: parsed.Value("name"),
+			Code: parsed.Value("code"),
+		}
 
-	case "name":
-		configuration.registrationStatus = status.Ok
+		// Keep the registration for future sessions only if it is genuine
+		if candidate.Verify() {
+			if err := candidate.Save(); err != nil {
+				logger.Error("Unable to store the registration: %s", err)
+			}
+		} else {
+			logger.Warn("Registration code is not valid for '%s'", candidate.Name)
+		}
 
-	case "code":
-		configuration.registrationStatus = status.Ok
+		configuration.registration = candidate
+		configuration.registrationStatus = status.Checking
 
 	default:
 		configuration.registrationStatus = status.Error
-		logger.Error("Malformed register command arguments: %s", arguments)
+		logger.Error("Malformed register command arguments: %s", strings.Join(arguments, " "))
 	}
 
 	// Confirm the change in status
@@ -238,42 +349,75 @@ func registerCommand(configuration *configuration, arguments string) bool {
 	return true
 }
 
-func setoptionCommand(configuration *configuration, arguments string) bool {
-	// Currently no options are supported
-	optionName, _ := utility.SplitNextWord(arguments)
-	if optionName != "" {
-		logger.Warn("Unexpected attempt to configure '%s'", optionName)
+// Process 'setoption'
+func setoptionCommand(configuration *configuration, arguments []string) bool {
+	parsed := utility.ParseArguments(arguments, "name", "value")
+	reportUnknownArguments(configuration, "setoption", parsed)
 
-		utility.WriteInfoString("Unknown option '%s'", optionName)
-	} else {
-		logger.Error("Malformed setoption command")
+	// Names and values may both contain spaces, as in 'name Clear Hash' or 'name Book File value C:\My Books\book.bin'
+	name := parsed.Value("name")
+	value := parsed.Value("value")
+	if name == "" {
+		logger.Error("Malformed setoption command: missing option name")
+		return true
+	}
+
+	if err := configuration.engine.SetOption(name, value); err != nil {
+		logger.Warn("Unable to set option: %s", err)
+
+		configuration.output.WriteInfoString("%s", err)
+		return true
 	}
 
+	logger.Debug("Option '%s' set to '%s'", name, configuration.engine.Options().Get(name).Value())
+
 	return true
 }
 
-func stopCommand(configuration *configuration, _ string) bool {
-	// TODO see if there is anything ongoing, and terminate it, including causing bestmove to be issued
+// Process 'stop'
+func stopCommand(configuration *configuration, _ []string) bool {
+	if !configuration.engine.Searching() {
+		logger.Debug("'stop' received with no search in progress")
+	}
+
+	// Wait for the search to finish so that 'bestmove' is written before we process anything else
+	configuration.engine.Stop(false)
 
 	return true
 }
 
+// Finish is called when input has ended. It lets any search with a natural end complete,
+// stops any that would otherwise wait indefinitely, and waits for the best move to be written.
+func Finish(configuration *configuration) {
+	configuration.engine.Finish()
+}
+
+// Quit ends the session as 'quit' does, when there is no longer anyone to send the best move to. It
+// terminates any search without it sending 'bestmove'.
+func Quit(configuration *configuration) {
+	configuration.engine.Stop(true)
+}
+
 // Process 'uci'
-func uciCommand(configuration *configuration, _ string) bool {
+func uciCommand(configuration *configuration, _ []string) bool {
 	if configuration.uciok {
 		// Log as error as this is a non-conformance with the UCI spec
 		logger.Error("'uci' command already issued")
 
 		if configuration.debug {
-			utility.WriteInfoString("'uci' command already issued")
+			configuration.output.WriteInfoString("'uci' command already issued")
 		}
 	}
 
-	utility.WriteId(identification.GetEngineName(), identification.GetAuthorName())
+	configuration.output.WriteId(identification.GetEngineName(), identification.GetAuthorName())
 
-	// TODO write any options we have
+	for _, engineOption := range configuration.engine.Options().Options() {
+		if !engineOption.Hidden() {
+			configuration.output.WriteOption(engineOption.Declaration())
+		}
+	}
 
-	utility.WriteUciOk()
+	configuration.output.WriteUciOk()
 
 	// Do the registration stuff
 	checkRegistration(configuration)
@@ -284,20 +428,40 @@ func uciCommand(configuration *configuration, _ string) bool {
 	return true
 }
 
-func ucinewgameCommand(configuration *configuration, _ string) bool {
+func ucinewgameCommand(configuration *configuration, _ []string) bool {
+	configuration.engine.NewGame()
 	return true
 }
 
+// reportUnknownArguments logs, and reports in debug mode, any tokens a command skipped because
+// they did not follow one of its keywords
+func reportUnknownArguments(configuration *configuration, command string, arguments utility.Arguments) {
+	unknown := arguments.Unknown()
+	if len(unknown) == 0 {
+		return
+	}
+
+	logger.Warn("Skipped unknown '%s' argument(s) '%s'", command, strings.Join(unknown, " "))
+
+	if configuration.debug {
+		configuration.output.WriteInfoString("Skipped unknown '%s' argument(s) '%s'", command, strings.Join(unknown, " "))
+	}
+}
+
 func checkRegistration(configuration *configuration) {
-	utility.WriteRegistrationStatus(configuration.registrationStatus)
+	configuration.output.WriteRegistrationStatus(configuration.registrationStatus)
 
 	if configuration.registrationStatus == status.Checking {
 
-		// TODO - check for registration and reset the status flag
-		configuration.registrationStatus = status.Ok
+		// Development builds need no registration, others need a code that matches the name
+		if !registration.Required() || configuration.registration.Verify() {
+			configuration.registrationStatus = status.Ok
+		} else {
+			configuration.registrationStatus = status.Error
+		}
 
 		// Notify the change in status
-		utility.WriteRegistrationStatus(configuration.registrationStatus)
+		configuration.output.WriteRegistrationStatus(configuration.registrationStatus)
 	}
 
 	// Reset things
@@ -305,14 +469,36 @@ func checkRegistration(configuration *configuration) {
 }
 
 func checkCopyProtection(configuration *configuration) {
-	utility.WriteCopyProtectionStatus(configuration.copyProtectionStatus)
+	configuration.output.WriteCopyProtectionStatus(configuration.copyProtectionStatus)
 
 	if configuration.copyProtectionStatus == status.Checking {
 
-		// TODO - check for any copy protection issues and set the status flag accordingly
+		// Development builds are not checked, others must match their signed manifest. The check is
+		// made once for the process, and its policy is applied by the engine to every search.
 		configuration.copyProtectionStatus = status.Ok
+		if protection.Result() != nil {
+			configuration.copyProtectionStatus = status.Error
+		}
 
 		// Notify the change in status
-		utility.WriteCopyProtectionStatus(configuration.copyProtectionStatus)
+		configuration.output.WriteCopyProtectionStatus(configuration.copyProtectionStatus)
+
+		if configuration.copyProtectionStatus == status.Error {
+			applyCopyProtectionPolicy(configuration)
+		}
+	}
+}
+
+// applyCopyProtectionPolicy tells the GUI what this build does when the integrity check fails
+func applyCopyProtectionPolicy(configuration *configuration) {
+	switch protection.GetPolicy() {
+	case protection.PolicyLimited:
+		configuration.output.WriteInfoString("The engine copy protection status is not ok. Playing at limited strength.")
+
+	case protection.PolicyRefuse:
+		configuration.output.WriteInfoString("The engine copy protection status is not ok. Searching is disabled.")
+
+	default:
+		configuration.output.WriteInfoString("The engine copy protection status is not ok")
 	}
 }
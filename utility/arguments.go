@@ -0,0 +1,86 @@
+package utility
+
+import (
+	"strings"
+)
+
+// Tokenize splits a line of input into tokens separated by any amount of whitespace, including tabs,
+// as the UCI protocol requires
+func Tokenize(input string) []string {
+	return strings.Fields(input)
+}
+
+// Arguments holds the tokens of a command grouped by the keyword that precedes them,
+// e.g. 'name Clear Hash' or 'wtime 1000 btime 1000'
+type Arguments struct {
+	values  map[string][]string
+	order   []string
+	unknown []string
+}
+
+// ParseArguments groups tokens by keyword. Each keyword collects the tokens that follow it, up to the
+// next keyword. Tokens that appear before the first keyword are kept aside as unknown, so that callers
+// can skip them as the protocol requires and report them in debug mode.
+//
+// Parameters:
+// - tokens: the tokens following the command itself
+// - keywords: the keywords recognised by the command
+//
+// Returns the grouped arguments.
+func ParseArguments(tokens []string, keywords ...string) Arguments {
+	arguments := Arguments{
+		values: make(map[string][]string),
+	}
+
+	isKeyword := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		isKeyword[keyword] = true
+	}
+
+	current := ""
+	for _, token := range tokens {
+		if isKeyword[token] {
+			current = token
+			if _, seen := arguments.values[current]; !seen {
+				arguments.order = append(arguments.order, current)
+			}
+			arguments.values[current] = []string{}
+			continue
+		}
+
+		if current == "" {
+			arguments.unknown = append(arguments.unknown, token)
+			continue
+		}
+
+		arguments.values[current] = append(arguments.values[current], token)
+	}
+
+	return arguments
+}
+
+// Has reports whether the keyword was present
+func (a Arguments) Has(keyword string) bool {
+	_, present := a.values[keyword]
+	return present
+}
+
+// Values returns the tokens that followed the keyword
+func (a Arguments) Values(keyword string) []string {
+	return a.values[keyword]
+}
+
+// Value returns the tokens that followed the keyword, joined by single spaces
+func (a Arguments) Value(keyword string) string {
+	return strings.Join(a.values[keyword], " ")
+}
+
+// Keywords returns the keywords that were present, in the order in which they first appeared
+func (a Arguments) Keywords() []string {
+	return a.order
+}
+
+// Unknown returns any tokens that appeared before the first keyword
+func (a Arguments) Unknown() []string {
+	return a.unknown
+}
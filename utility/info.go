@@ -0,0 +1,254 @@
+package utility
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bound describes whether a score is exact or only a bound on the true score
+type Bound int
+
+const (
+	BoundExact Bound = iota
+	BoundLower
+	BoundUpper
+)
+
+// Score is a search score, either in centipawns or as a number of moves to mate
+type Score struct {
+	Value int
+	Mate  bool
+	Bound Bound
+}
+
+// ScoreCentipawns creates an exact score in centipawns from the engine's point of view
+func ScoreCentipawns(centipawns int) Score {
+	return Score{Value: centipawns}
+}
+
+// ScoreMate creates an exact mate score in moves (not plies). Negative values mean the engine is getting mated
+func ScoreMate(moves int) Score {
+	return Score{Value: moves, Mate: true}
+}
+
+// WithBound returns a copy of the score qualified by the bound
+func (s Score) WithBound(bound Bound) Score {
+	s.Bound = bound
+	return s
+}
+
+func (s Score) String() string {
+	text := fmt.Sprintf("%s %d", If(s.Mate, "mate", "cp"), s.Value)
+
+	switch s.Bound {
+	case BoundLower:
+		text += " lowerbound"
+	case BoundUpper:
+		text += " upperbound"
+	}
+
+	return text
+}
+
+// Info collects the fields for a single 'info' line. Only the fields that have been set are written,
+// and they are written in a consistent order regardless of the order in which they were set.
+type Info struct {
+	depth          *int
+	selDepth       *int
+	multiPV        *int
+	score          *Score
+	nodes          *uint64
+	nps            *uint64
+	hashFull       *int
+	tbHits         *uint64
+	time           *time.Duration
+	currMove       string
+	currMoveNumber *int
+	pv             []string
+	refutation     []string
+	currLine       []string
+	currLineCPU    *int
+}
+
+// NewInfo creates an empty Info, ready for fields to be added
+func NewInfo() *Info {
+	return &Info{}
+}
+
+func (i *Info) Depth(depth int) *Info {
+	i.depth = &depth
+	return i
+}
+
+func (i *Info) SelDepth(selDepth int) *Info {
+	i.selDepth = &selDepth
+	return i
+}
+
+func (i *Info) MultiPV(index int) *Info {
+	i.multiPV = &index
+	return i
+}
+
+func (i *Info) Score(score Score) *Info {
+	i.score = &score
+	return i
+}
+
+func (i *Info) Nodes(nodes uint64) *Info {
+	i.nodes = &nodes
+	return i
+}
+
+func (i *Info) NPS(nps uint64) *Info {
+	i.nps = &nps
+	return i
+}
+
+// HashFull sets how full the hash table is, in permille
+func (i *Info) HashFull(permille int) *Info {
+	i.hashFull = &permille
+	return i
+}
+
+func (i *Info) TBHits(hits uint64) *Info {
+	i.tbHits = &hits
+	return i
+}
+
+// Time sets the time searched, which is written in milliseconds
+func (i *Info) Time(elapsed time.Duration) *Info {
+	i.time = &elapsed
+	return i
+}
+
+// CurrMove sets the move currently being searched and its 1-based position in the move list
+func (i *Info) CurrMove(move string, number int) *Info {
+	i.currMove = move
+	i.currMoveNumber = &number
+	return i
+}
+
+func (i *Info) PV(moves ...string) *Info {
+	i.pv = moves
+	return i
+}
+
+// Refutation sets a move followed by the line that refutes it
+func (i *Info) Refutation(moves ...string) *Info {
+	i.refutation = moves
+	return i
+}
+
+// CurrLine sets the line being searched by a CPU. A cpu number of zero is omitted, as for a single CPU
+func (i *Info) CurrLine(cpu int, moves ...string) *Info {
+	i.currLineCPU = &cpu
+	i.currLine = moves
+	return i
+}
+
+// String returns the text that follows 'info'
+func (i *Info) String() string {
+	var fields []string
+
+	add := func(format string, args ...interface{}) {
+		fields = append(fields, fmt.Sprintf(format, args...))
+	}
+
+	if i.depth != nil {
+		add("depth %d", *i.depth)
+	}
+	if i.selDepth != nil {
+		add("seldepth %d", *i.selDepth)
+	}
+	if i.multiPV != nil {
+		add("multipv %d", *i.multiPV)
+	}
+	if i.score != nil {
+		add("score %s", i.score)
+	}
+	if i.nodes != nil {
+		add("nodes %d", *i.nodes)
+	}
+	if i.nps != nil {
+		add("nps %d", *i.nps)
+	}
+	if i.hashFull != nil {
+		add("hashfull %d", *i.hashFull)
+	}
+	if i.tbHits != nil {
+		add("tbhits %d", *i.tbHits)
+	}
+	if i.time != nil {
+		add("time %d", i.time.Milliseconds())
+	}
+	if i.currMove != "" {
+		add("currmove %s", i.currMove)
+	}
+	if i.currMoveNumber != nil {
+		add("currmovenumber %d", *i.currMoveNumber)
+	}
+	if len(i.refutation) > 0 {
+		add("refutation %s", strings.Join(i.refutation, " "))
+	}
+	if i.currLineCPU != nil {
+		if *i.currLineCPU > 0 {
+			add("currline %d %s", *i.currLineCPU, strings.Join(i.currLine, " "))
+		} else {
+			add("currline %s", strings.Join(i.currLine, " "))
+		}
+	}
+
+	// The PV goes last as it runs to the end of the line
+	if len(i.pv) > 0 {
+		add("pv %s", strings.Join(i.pv, " "))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// Defaults for throttling periodic search information
+const (
+	DefaultInfoDelay    = time.Second
+	DefaultInfoInterval = 500 * time.Millisecond
+)
+
+// InfoThrottle limits how often periodic search information (current move, current line, node counts
+// and bound updates) is written, so that fast searches do not flood the GUI. Nothing periodic is allowed
+// until the search has run for the initial delay, and then no more often than once per interval.
+type InfoThrottle struct {
+	mutex    sync.Mutex
+	start    time.Time
+	last     time.Time
+	delay    time.Duration
+	interval time.Duration
+}
+
+// NewInfoThrottle creates a throttle whose delay starts from now
+func NewInfoThrottle(delay time.Duration, interval time.Duration) *InfoThrottle {
+	return &InfoThrottle{
+		start:    time.Now(),
+		delay:    delay,
+		interval: interval,
+	}
+}
+
+// Allow reports whether a periodic info line may be written now, and records it if so
+func (t *InfoThrottle) Allow() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.start) < t.delay {
+		return false
+	}
+
+	if !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		return false
+	}
+
+	t.last = now
+	return true
+}
@@ -1,46 +1,114 @@
 package utility
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 
 	// Internal references
 	"goche/status"
 )
 
+// Writer serializes all engine output, so that lines written by the command loop, search
+// and perft goroutines can never interleave. Each line is flushed as soon as it is complete.
+type Writer struct {
+	mutex  sync.Mutex
+	output *bufio.Writer
+	taps   []func(line string)
+}
+
+// NewWriter creates a Writer for the given destination
+func NewWriter(output io.Writer) *Writer {
+	return &Writer{
+		output: bufio.NewWriter(output),
+	}
+}
+
+// NewStandardWriter creates a Writer for stdout
+func NewStandardWriter() *Writer {
+	return NewWriter(os.Stdout)
+}
+
+// AddTap registers a function that is given a copy of each line written, for example for logging
+// or for checking a script's expectations. Taps are called in the order they were added, while the
+// writer is locked, so they must not write to the writer themselves.
+func (w *Writer) AddTap(tap func(line string)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.taps = append(w.taps, tap)
+}
+
 // Write the engine identification information
-func WriteId(engineName string, authorName string) {
-	write("id name %s", engineName)
-	write("id author %s", authorName)
+func (w *Writer) WriteId(engineName string, authorName string) {
+	w.write("id name %s", engineName)
+	w.write("id author %s", authorName)
 }
 
 // Write an 'info string'
-func WriteInfoString(format string, args ...interface{}) {
+func (w *Writer) WriteInfoString(format string, args ...interface{}) {
 	information := fmt.Sprintf(format, args...)
-	write("info string %s", information)
+	w.write("info string %s", information)
+}
+
+// Write an option declaration in response to 'uci'
+func (w *Writer) WriteOption(declaration string) {
+	w.write("option %s", declaration)
+}
+
+// Write an 'info' line with search information
+func (w *Writer) WriteInfo(info *Info) {
+	w.write("info %s", info)
 }
 
 // Write 'readyok'
-func WriteReadyOk() {
-	write("readyok")
+func (w *Writer) WriteReadyOk() {
+	w.write("readyok")
+}
+
+// Write 'bestmove', with a ponder move if there is one
+func (w *Writer) WriteBestMove(move string, ponderMove string) {
+	if ponderMove == "" {
+		w.write("bestmove %s", move)
+	} else {
+		w.write("bestmove %s ponder %s", move, ponderMove)
+	}
 }
 
 // Write the copy protection status
-func WriteCopyProtectionStatus(status status.Status) {
-	write("copyprotection %s", status)
+func (w *Writer) WriteCopyProtectionStatus(status status.Status) {
+	w.write("copyprotection %s", status)
 }
 
 // Write the registration status
-func WriteRegistrationStatus(status status.Status) {
-	write("registration %s", status)
+func (w *Writer) WriteRegistrationStatus(status status.Status) {
+	w.write("registration %s", status)
 }
 
 // Write the 'uciok' message
-func WriteUciOk() {
-	write("uciok")
+func (w *Writer) WriteUciOk() {
+	w.write("uciok")
 }
 
-// Internal print function
-func write(format string, args ...interface{}) {
-	fmt.Printf(format, args...)
-	fmt.Println()
+// Write a line of free-form text, for the output of bespoke commands such as 'perft'
+func (w *Writer) WriteLine(format string, args ...interface{}) {
+	w.write(format, args...)
+}
+
+// Internal print function - the only place that engine output is written
+func (w *Writer) write(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.output.WriteString(line)
+	w.output.WriteByte('\n')
+	w.output.Flush()
+
+	for _, tap := range w.taps {
+		tap(line)
+	}
 }
@@ -0,0 +1,671 @@
+package xboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Internal references
+	"goche/chess"
+	"goche/engine"
+	"goche/identification"
+	"goche/logger"
+	"goche/utility"
+)
+
+// Command is the handler for a CECP command. The session is locked while it runs.
+type Command func(*Session, []string) bool
+
+var commands = map[string]Command{
+	// Protocol and session
+	"xboard":   xboardCommand,
+	"protover": protoverCommand,
+	"accepted": ignoredCommand,
+	"rejected": ignoredCommand,
+	"ping":     pingCommand,
+	"quit":     quitCommand,
+
+	// Game control
+	"new":       newCommand,
+	"variant":   variantCommand,
+	"force":     forceCommand,
+	"go":        goCommand,
+	"playother": playotherCommand,
+	"usermove":  usermoveCommand,
+	"?":         moveNowCommand,
+	"setboard":  setboardCommand,
+	"undo":      undoCommand,
+	"remove":    removeCommand,
+	"result":    resultCommand,
+
+	// Time controls
+	"level": levelCommand,
+	"st":    stCommand,
+	"sd":    sdCommand,
+	"time":  timeCommand,
+	"otim":  otimCommand,
+
+	// Analysis
+	"analyze": analyzeCommand,
+	"exit":    exitCommand,
+	".":       ignoredCommand,
+	"post":    postCommand,
+	"nopost":  nopostCommand,
+
+	// Accepted but with no effect on this engine
+	"hard":     ignoredCommand,
+	"easy":     ignoredCommand,
+	"random":   ignoredCommand,
+	"computer": ignoredCommand,
+	"name":     ignoredCommand,
+	"rating":   ignoredCommand,
+	"draw":     ignoredCommand,
+	"ics":      ignoredCommand,
+}
+
+// Session is the state of a game played, or analysed, through the Chess Engine Communication Protocol
+// used by XBoard and WinBoard
+type Session struct {
+	output *utility.Writer
+	mutex  sync.Mutex
+
+	board   chess.Board
+	history []chess.Board
+
+	// Which side the engine plays, unless in force mode, when it plays neither
+	forceMode   bool
+	engineWhite bool
+	analyzing   bool
+	post        bool
+
+	// Time controls
+	depth           int
+	moveTime        time.Duration
+	movesPerSession int
+	baseTime        time.Duration
+	increment       time.Duration
+	engineTime      time.Duration
+	opponentTime    time.Duration
+
+	// Whether 'time' and 'otim' have reported the clocks since the game began
+	engineTimeSet   bool
+	opponentTimeSet bool
+
+	// The engine, whether it is searching, and a count of searches started so that the result of one
+	// that has been abandoned can be recognised and discarded
+	engine     *engine.Engine
+	searching  bool
+	generation int
+}
+
+// NewSession creates a session for a new game, with the engine playing black
+func NewSession(output *utility.Writer) *Session {
+	// Until told otherwise, assume XBoard's own default time control of 40 moves in 5 minutes
+	s := &Session{
+		output:          output,
+		engine:          engine.New(),
+		movesPerSession: 40,
+		baseTime:        5 * time.Minute,
+	}
+	s.reset()
+
+	return s
+}
+
+// ProcessCommand processes a single line of CECP input.
+//
+// Returns false if the engine should quit, true otherwise.
+func ProcessCommand(s *Session, input string) bool {
+	tokens := utility.Tokenize(input)
+	if len(tokens) == 0 {
+		return true
+	}
+
+	command := tokens[0]
+	arguments := tokens[1:]
+
+	logger.Debug("Received '%s' command", command)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	handler := commands[command]
+	if handler == nil {
+		// Before 'protover 2', moves are sent without the 'usermove' prefix
+		if isMoveString(command) {
+			return usermoveCommand(s, tokens)
+		}
+
+		s.output.WriteLine("Error (unknown command): %s", command)
+		return true
+	}
+
+	return handler(s, arguments)
+}
+
+// Finish is called when input has ended. It abandons any analysis and lets the engine complete a
+// move it is thinking about.
+func Finish(s *Session) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.analyzing {
+		s.stopSearch()
+		return
+	}
+
+	s.waitForSearch()
+}
+
+// Quit ends the session as 'quit' does, when there is no longer anyone to send a move to. It
+// terminates any search without it playing a move.
+func Quit(s *Session) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopSearch()
+}
+
+// Process 'xboard'
+func xboardCommand(_ *Session, _ []string) bool {
+	// Nothing to do; the protocol was selected by this command
+	return true
+}
+
+// Process 'protover'
+func protoverCommand(s *Session, arguments []string) bool {
+	if len(arguments) == 0 {
+		logger.Error("Malformed protover command: missing version")
+		return true
+	}
+
+	version, err := strconv.Atoi(arguments[0])
+	if err != nil || version < 2 {
+		// Version 1 interfaces do not understand features
+		return true
+	}
+
+	name := fmt.Sprintf("%s %s", identification.GetEngineName(), identification.GetVersionName())
+	s.output.WriteLine("feature myname=\"%s\" variants=\"normal\"", name)
+	s.output.WriteLine("feature ping=1 setboard=1 playother=1 usermove=1 san=0 time=1 draw=0 analyze=1 colors=0")
+	s.output.WriteLine("feature sigint=0 sigterm=0 reuse=1 name=0 done=1")
+
+	return true
+}
+
+// Process commands that need no response and change nothing
+func ignoredCommand(_ *Session, _ []string) bool {
+	return true
+}
+
+// Process 'ping'
+func pingCommand(s *Session, arguments []string) bool {
+	// 'pong' must follow the effects of every earlier command, including a move being thought about
+	if !s.analyzing {
+		s.waitForSearch()
+	}
+
+	s.output.WriteLine("pong %s", strings.Join(arguments, " "))
+	return true
+}
+
+// Process 'quit'
+func quitCommand(s *Session, _ []string) bool {
+	s.stopSearch()
+	return false
+}
+
+// Process 'new'
+func newCommand(s *Session, _ []string) bool {
+	s.stopSearch()
+	s.reset()
+	s.engine.NewGame()
+
+	s.resume()
+	return true
+}
+
+// Process 'variant'
+func variantCommand(s *Session, arguments []string) bool {
+	if len(arguments) == 0 || arguments[0] != "normal" {
+		s.output.WriteLine("Error (unsupported variant): %s", strings.Join(arguments, " "))
+	}
+	return true
+}
+
+// Process 'force'
+func forceCommand(s *Session, _ []string) bool {
+	s.forceMode = true
+	if !s.analyzing {
+		s.stopSearch()
+	}
+	return true
+}
+
+// Process 'result', which ends the game
+func resultCommand(s *Session, _ []string) bool {
+	s.stopSearch()
+	s.forceMode = true
+	return true
+}
+
+// Process 'go'
+func goCommand(s *Session, _ []string) bool {
+	s.forceMode = false
+	s.engineWhite = s.board.IsWhiteToMove()
+
+	s.think()
+	return true
+}
+
+// Process 'playother'
+func playotherCommand(s *Session, _ []string) bool {
+	s.forceMode = false
+	s.engineWhite = !s.board.IsWhiteToMove()
+	return true
+}
+
+// Process 'usermove'
+func usermoveCommand(s *Session, arguments []string) bool {
+	if len(arguments) == 0 {
+		s.output.WriteLine("Error (missing move): usermove")
+		return true
+	}
+
+	move, err := s.board.FindMove(arguments[0])
+	if err != nil {
+		s.output.WriteLine("Illegal move: %s", arguments[0])
+		return true
+	}
+
+	s.stopSearch()
+	s.makeMove(move)
+
+	s.resume()
+	return true
+}
+
+// Process '?', which asks the engine to move now
+func moveNowCommand(s *Session, _ []string) bool {
+	if !s.searching || s.analyzing {
+		return true
+	}
+
+	// The result is reported, and the move played, by the search itself
+	s.mutex.Unlock()
+	s.engine.Stop(false)
+	s.mutex.Lock()
+
+	return true
+}
+
+// Process 'setboard'
+func setboardCommand(s *Session, arguments []string) bool {
+	board, err := chess.NewBoard(strings.Join(arguments, " "))
+	if err != nil {
+		logger.Error("Malformed setboard command: %s", err)
+		s.output.WriteLine("tellusererror Illegal position")
+		return true
+	}
+
+	s.stopSearch()
+	s.board = *board
+	s.history = nil
+
+	s.resume()
+	return true
+}
+
+// Process 'undo'
+func undoCommand(s *Session, _ []string) bool {
+	s.takeBack(1)
+	return true
+}
+
+// Process 'remove'
+func removeCommand(s *Session, _ []string) bool {
+	s.takeBack(2)
+	return true
+}
+
+// Process 'level', e.g. 'level 40 5 0' or 'level 0 2:30 1'
+func levelCommand(s *Session, arguments []string) bool {
+	if len(arguments) != 3 {
+		s.output.WriteLine("Error (malformed level): %s", strings.Join(arguments, " "))
+		return true
+	}
+
+	movesPerSession, err1 := strconv.Atoi(arguments[0])
+	baseTime, err2 := parseBaseTime(arguments[1])
+	increment, err3 := strconv.ParseFloat(arguments[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		s.output.WriteLine("Error (malformed level): %s", strings.Join(arguments, " "))
+		return true
+	}
+
+	s.movesPerSession = movesPerSession
+	s.baseTime = baseTime
+	s.increment = time.Duration(increment * float64(time.Second))
+	s.moveTime = 0
+
+	return true
+}
+
+// Process 'st', which sets an exact time per move in seconds
+func stCommand(s *Session, arguments []string) bool {
+	seconds, err := parseNumber(arguments)
+	if err != nil {
+		s.output.WriteLine("Error (malformed st): %s", strings.Join(arguments, " "))
+		return true
+	}
+
+	s.moveTime = time.Duration(seconds * float64(time.Second))
+	return true
+}
+
+// Process 'sd', which limits the search depth
+func sdCommand(s *Session, arguments []string) bool {
+	depth, err := parseNumber(arguments)
+	if err != nil || depth < 1 {
+		s.output.WriteLine("Error (malformed sd): %s", strings.Join(arguments, " "))
+		return true
+	}
+
+	s.depth = int(depth)
+	return true
+}
+
+// Process 'time', the engine's remaining time in centiseconds
+func timeCommand(s *Session, arguments []string) bool {
+	centiseconds, err := parseNumber(arguments)
+	if err != nil {
+		s.output.WriteLine("Error (malformed time): %s", strings.Join(arguments, " "))
+		return true
+	}
+
+	// A flagged engine may be sent a negative time
+	s.engineTime = max(time.Duration(centiseconds*float64(10*time.Millisecond)), 0)
+	s.engineTimeSet = true
+	return true
+}
+
+// Process 'otim', the opponent's remaining time in centiseconds
+func otimCommand(s *Session, arguments []string) bool {
+	centiseconds, err := parseNumber(arguments)
+	if err != nil {
+		s.output.WriteLine("Error (malformed otim): %s", strings.Join(arguments, " "))
+		return true
+	}
+
+	s.opponentTime = max(time.Duration(centiseconds*float64(10*time.Millisecond)), 0)
+	s.opponentTimeSet = true
+	return true
+}
+
+// Process 'analyze'
+func analyzeCommand(s *Session, _ []string) bool {
+	s.stopSearch()
+	s.analyzing = true
+
+	s.resume()
+	return true
+}
+
+// Process 'exit', which leaves analysis mode
+func exitCommand(s *Session, _ []string) bool {
+	if !s.analyzing {
+		return true
+	}
+
+	s.stopSearch()
+	s.analyzing = false
+	return true
+}
+
+// Process 'post'
+func postCommand(s *Session, _ []string) bool {
+	s.post = true
+	return true
+}
+
+// Process 'nopost'
+func nopostCommand(s *Session, _ []string) bool {
+	s.post = false
+	return true
+}
+
+// reset sets up a new game, with the engine playing black, no depth limit and the clocks at the
+// start of the time control
+func (s *Session) reset() {
+	board, _ := chess.NewBoard(chess.FenStartingPosition)
+	s.board = *board
+	s.history = nil
+	s.forceMode = false
+	s.engineWhite = false
+	s.depth = 0
+	s.engineTimeSet = false
+	s.opponentTimeSet = false
+}
+
+// makeMove plays a move, keeping the previous position so that it can be taken back
+func (s *Session) makeMove(move chess.Move) {
+	s.history = append(s.history, s.board)
+	s.board.MakeMove(move)
+}
+
+// takeBack retracts moves, if there are enough to retract
+func (s *Session) takeBack(count int) {
+	if len(s.history) < count {
+		s.output.WriteLine("Error (no moves to take back): %s", utility.If(count == 1, "undo", "remove"))
+		return
+	}
+
+	s.stopSearch()
+	s.board = s.history[len(s.history)-count]
+	s.history = s.history[:len(s.history)-count]
+
+	s.resume()
+}
+
+// resume restarts analysis after the position changes, or starts thinking if it is the engine's move
+func (s *Session) resume() {
+	if s.analyzing {
+		s.think()
+		return
+	}
+
+	if !s.forceMode && s.engineWhite == s.board.IsWhiteToMove() {
+		s.think()
+	}
+}
+
+// think starts a search of the current position, either to analyse it or to choose the engine's move
+func (s *Session) think() {
+	s.stopSearch()
+
+	if result := gameResult(&s.board); result != "" {
+		if !s.analyzing {
+			s.output.WriteLine("%s", result)
+		}
+		return
+	}
+
+	limits := engine.Limits{
+		Depth:    s.depth,
+		MoveTime: s.moveTime,
+		Infinite: s.analyzing,
+	}
+
+	if !s.analyzing && s.moveTime == 0 {
+		// Without 'time' and 'otim' the clocks are taken to be at the start of the time control
+		engineTime := utility.If(s.engineTimeSet, s.engineTime, s.baseTime)
+		opponentTime := utility.If(s.opponentTimeSet, s.opponentTime, s.baseTime)
+
+		limits.SetClock(s.board.IsWhiteToMove(), engineTime, s.increment)
+		limits.SetClock(!s.board.IsWhiteToMove(), opponentTime, s.increment)
+
+		// In a classical time control, count the moves to the next time control
+		if s.movesPerSession > 0 {
+			movesPlayed := len(s.history) / 2
+			limits.MovesToGo = s.movesPerSession - movesPlayed%s.movesPerSession
+		}
+	}
+
+	s.generation++
+	s.searching = true
+	s.engine.SetPosition(&s.board)
+	s.engine.Go(limits, &reporter{session: s, generation: s.generation, analysis: s.analyzing, post: s.post})
+}
+
+// stopSearch abandons any search in progress, discarding its result
+func (s *Session) stopSearch() {
+	if !s.searching {
+		return
+	}
+
+	s.searching = false
+	s.generation++
+
+	// The search may need the lock to finish reporting, so release it while we wait
+	s.mutex.Unlock()
+	s.engine.Stop(true)
+	s.mutex.Lock()
+}
+
+// waitForSearch waits for a search with a natural end to finish and play its move
+func (s *Session) waitForSearch() {
+	if !s.searching {
+		return
+	}
+
+	s.mutex.Unlock()
+	s.engine.Wait()
+	s.mutex.Lock()
+}
+
+// reporter writes the progress of a search as CECP thinking output, and plays the engine's move
+type reporter struct {
+	session    *Session
+	generation int
+	analysis   bool
+	post       bool
+}
+
+// Thinking writes a line of thinking output: ply, score, time in centiseconds, nodes and principal variation
+func (r *reporter) Thinking(progress engine.Progress) {
+	// Analysis output is always shown
+	if !r.post && !r.analysis {
+		return
+	}
+
+	if len(progress.PV) == 0 {
+		return
+	}
+
+	r.session.output.WriteLine("%d %d %d %d %s", progress.Depth, xboardScore(progress.Score),
+		progress.Elapsed.Milliseconds()/10, progress.Nodes, strings.Join(progress.PV, " "))
+}
+
+// BestMove plays the engine's move, unless the search has been abandoned
+func (r *reporter) BestMove(bestMove string, _ string) {
+	s := r.session
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.generation != s.generation || r.analysis {
+		return
+	}
+	s.searching = false
+
+	// A game that is over is not searched, so there is no move only when the search was refused
+	if bestMove == chess.NullMoveString {
+		s.output.WriteLine("tellusererror The engine copy protection status is not ok. Searching is disabled.")
+		return
+	}
+
+	move, err := s.board.FindMove(bestMove)
+	if err != nil {
+		logger.Error("Search returned an illegal move '%s': %s", bestMove, err)
+		return
+	}
+
+	s.makeMove(move)
+	s.output.WriteLine("move %s", bestMove)
+
+	if result := gameResult(&s.board); result != "" {
+		s.output.WriteLine("%s", result)
+	}
+}
+
+// xboardScore converts a score to centipawns, with mates given as 100000 + moves to mate, as XBoard expects
+func xboardScore(score utility.Score) int {
+	if !score.Mate {
+		return score.Value
+	}
+
+	if score.Value < 0 {
+		return -100000 + score.Value
+	}
+	return 100000 + score.Value
+}
+
+// gameResult returns the CECP result if the game is over, or an empty string
+func gameResult(board *chess.Board) string {
+	switch board.Status() {
+	case chess.Stalemate:
+		return "1/2-1/2 {Stalemate}"
+	case chess.FiftyMoveRule:
+		return "1/2-1/2 {50 move rule}"
+	case chess.InsufficientMaterial:
+		return "1/2-1/2 {Insufficient material}"
+	case chess.Checkmate:
+		if board.IsWhiteToMove() {
+			return "0-1 {Black mates}"
+		}
+		return "1-0 {White mates}"
+	}
+
+	return ""
+}
+
+// parseBaseTime reads the base time of a 'level' command, which is either minutes or minutes:seconds
+func parseBaseTime(text string) (time.Duration, error) {
+	minutesText, secondsText, hasSeconds := strings.Cut(text, ":")
+
+	minutes, err := strconv.Atoi(minutesText)
+	if err != nil {
+		return 0, err
+	}
+
+	seconds := 0
+	if hasSeconds {
+		seconds, err = strconv.Atoi(secondsText)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, nil
+}
+
+// parseNumber reads the single numeric argument of a command
+func parseNumber(arguments []string) (float64, error) {
+	if len(arguments) != 1 {
+		return 0, fmt.Errorf("expected a single number")
+	}
+
+	return strconv.ParseFloat(arguments[0], 64)
+}
+
+// isMoveString reports whether the text looks like a move in coordinate notation (e.g. e2e4, e7e8q)
+func isMoveString(text string) bool {
+	if len(text) != 4 && len(text) != 5 {
+		return false
+	}
+
+	return text[0] >= 'a' && text[0] <= 'h' && text[1] >= '1' && text[1] <= '8' &&
+		text[2] >= 'a' && text[2] <= 'h' && text[3] >= '1' && text[3] <= '8'
+}
@@ -0,0 +1,264 @@
+package xboard_test
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	// Internal references
+	"goche/protocol"
+	"goche/utility"
+)
+
+// gui drives a session as XBoard would, reading the engine's output line by line
+type gui struct {
+	t          *testing.T
+	dispatcher *protocol.Dispatcher
+
+	mutex sync.Mutex
+	lines []string
+	read  int
+}
+
+// start creates a session whose protocol is chosen by the first command, as for standard input
+func start(t *testing.T) *gui {
+	g := &gui{t: t}
+
+	output := utility.NewWriter(io.Discard)
+	output.AddTap(func(line string) {
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+
+		g.lines = append(g.lines, line)
+	})
+
+	g.dispatcher = protocol.New(output)
+	t.Cleanup(g.dispatcher.Quit)
+
+	return g
+}
+
+func (g *gui) send(command string) {
+	g.dispatcher.Process(command)
+}
+
+// next returns the next line of output, or false if there is none within the timeout
+func (g *gui) next(timeout time.Duration) (string, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		g.mutex.Lock()
+		if g.read < len(g.lines) {
+			line := g.lines[g.read]
+			g.read++
+			g.mutex.Unlock()
+			return line, true
+		}
+		g.mutex.Unlock()
+
+		if time.Now().After(deadline) {
+			return "", false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// expect reads lines until one starts with the prefix, failing if none does within the timeout. It
+// returns the lines read before that one, and the line itself.
+func (g *gui) expect(prefix string, timeout time.Duration) ([]string, string) {
+	g.t.Helper()
+
+	var skipped []string
+	deadline := time.Now().Add(timeout)
+	for {
+		line, found := g.next(time.Until(deadline))
+		if !found {
+			g.t.Fatalf("Waiting for '%s', read %q", prefix, skipped)
+		}
+		if strings.HasPrefix(line, prefix) {
+			return skipped, line
+		}
+		skipped = append(skipped, line)
+	}
+}
+
+// thinking returns the ply of each line of thinking output
+func thinking(t *testing.T, lines []string) []int {
+	t.Helper()
+
+	var plies []int
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		if ply, err := strconv.Atoi(fields[0]); err == nil {
+			plies = append(plies, ply)
+		}
+	}
+	return plies
+}
+
+func TestProtocolDetection(t *testing.T) {
+	g := start(t)
+	g.send("xboard")
+
+	// Once XBoard has been chosen, UCI commands are not understood
+	g.send("uci")
+	g.expect("Error (unknown command): uci", time.Second)
+
+	g = start(t)
+	g.send("uci")
+	g.expect("uciok", time.Second)
+}
+
+func TestProtover(t *testing.T) {
+	g := start(t)
+	g.send("xboard")
+	g.send("protover 2")
+
+	features := ""
+	for !strings.Contains(features, "done=1") {
+		_, line := g.expect("feature ", time.Second)
+		features += line + " "
+	}
+
+	for _, feature := range []string{"myname=", "variants=\"normal\"", "ping=1", "setboard=1", "usermove=1", "time=1", "analyze=1"} {
+		if !strings.Contains(features, feature) {
+			t.Errorf("Feature %s missing from %s", feature, features)
+		}
+	}
+
+	// Version 1 interfaces are not sent features
+	g = start(t)
+	g.send("xboard")
+	g.send("protover 1")
+	g.send("ping 1")
+	if skipped, _ := g.expect("pong 1", time.Second); len(skipped) != 0 {
+		t.Errorf("Wrote %q for protover 1", skipped)
+	}
+}
+
+func TestUsermoveForceGo(t *testing.T) {
+	g := start(t)
+	g.send("xboard")
+	g.send("protover 2")
+	g.send("new")
+	g.send("sd 1")
+
+	// The engine plays black, so replies to white's move
+	g.send("usermove e2e4")
+	g.expect("move ", 5*time.Second)
+
+	// In force mode moves are only played, until 'go' has the engine play the side to move
+	g.send("force")
+	g.send("usermove d2d4")
+	g.send("usermove d7d5")
+	g.send("ping 1")
+	if skipped, _ := g.expect("pong 1", time.Second); len(skipped) != 0 {
+		t.Errorf("Wrote %q in force mode", skipped)
+	}
+
+	g.send("go")
+	g.expect("move ", 5*time.Second)
+
+	// Now playing white, the engine replies to black's moves
+	g.send("usermove a7a6")
+	g.expect("move ", 5*time.Second)
+
+	g.send("usermove e2e4")
+	g.expect("Illegal move: e2e4", time.Second)
+}
+
+func TestLevelStSd(t *testing.T) {
+	g := start(t)
+	g.send("xboard")
+	g.send("protover 2")
+	g.send("post")
+
+	// 'sd' limits the depth of the search
+	g.send("new")
+	g.send("sd 3")
+	g.send("go")
+	skipped, _ := g.expect("move ", 10*time.Second)
+	if plies := thinking(t, skipped); len(plies) == 0 || plies[len(plies)-1] != 3 {
+		t.Errorf("Searched to plies %v with sd 3", plies)
+	}
+
+	// 'st' sets the time for each move
+	g.send("new")
+	g.send("st 0.5")
+	g.send("go")
+	begun := time.Now()
+	g.expect("move ", 5*time.Second)
+	if elapsed := time.Since(begun); elapsed < 300*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Moved after %s with st 0.5", elapsed)
+	}
+
+	// 'level' replaces 'st', and with no 'time' the clock is taken to be the whole second of it
+	g.send("new")
+	g.send("level 0 0:01 0")
+	g.send("go")
+	begun = time.Now()
+	g.expect("move ", 5*time.Second)
+	if elapsed := time.Since(begun); elapsed > time.Second {
+		t.Errorf("Moved after %s with a second for the game", elapsed)
+	}
+
+	g.send("level 40 5")
+	g.expect("Error (malformed level): 40 5", time.Second)
+}
+
+func TestNoTimeLeft(t *testing.T) {
+	// Had the engine planned with the five minutes of the level, it would think for several seconds
+	for _, centiseconds := range []string{"0", "-50"} {
+		g := start(t)
+		g.send("xboard")
+		g.send("protover 2")
+		g.send("new")
+		g.send("level 40 5 0")
+		g.send("time " + centiseconds)
+		g.send("otim 30000")
+		g.send("go")
+
+		begun := time.Now()
+		g.expect("move ", 5*time.Second)
+		if elapsed := time.Since(begun); elapsed > time.Second {
+			t.Errorf("Moved after %s with time %s", elapsed, centiseconds)
+		}
+	}
+}
+
+func TestAnalyzeExit(t *testing.T) {
+	g := start(t)
+	g.send("xboard")
+	g.send("protover 2")
+	g.send("new")
+	g.send("force")
+
+	// Analysis output is shown without 'post', and the search never ends with a move
+	g.send("analyze")
+	skipped, _ := g.expect("2 ", 5*time.Second)
+	for _, line := range skipped {
+		if strings.HasPrefix(line, "move ") {
+			t.Errorf("Played '%s' while analysing", line)
+		}
+	}
+
+	// Moves played while analysing restart the analysis of the new position
+	g.send("usermove e2e4")
+	g.expect("1 ", 5*time.Second)
+
+	// After 'exit' the search has stopped, so nothing is written before 'pong'
+	g.send("exit")
+	g.mutex.Lock()
+	g.read = len(g.lines)
+	g.mutex.Unlock()
+
+	g.send("ping 1")
+	if skipped, _ := g.expect("pong 1", time.Second); len(skipped) != 0 {
+		t.Errorf("Wrote %q after exit", skipped)
+	}
+}